@@ -0,0 +1,76 @@
+// Copyright 2018 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package uio
+
+// Read4 reads a 4-byte array, such as a magic number, from the Buffer. The
+// array is a copy that does not alias the Buffer, so it can be kept after
+// later writes.
+//
+// If an error occurred, Error() will return a non-nil error and the array
+// is all zeroes.
+func (l *Lexer) Read4() [4]byte {
+	var a [4]byte
+	copy(a[:], l.consume(len(a)))
+	return a
+}
+
+// Read8b reads an 8-byte array from the Buffer. The b distinguishes it from
+// Read8, which reads a single byte.
+//
+// If an error occurred, Error() will return a non-nil error and the array
+// is all zeroes.
+func (l *Lexer) Read8b() [8]byte {
+	var a [8]byte
+	copy(a[:], l.consume(len(a)))
+	return a
+}
+
+// Read16b reads a 16-byte array, such as an MD5 digest, from the Buffer.
+//
+// If an error occurred, Error() will return a non-nil error and the array
+// is all zeroes.
+func (l *Lexer) Read16b() [16]byte {
+	var a [16]byte
+	copy(a[:], l.consume(len(a)))
+	return a
+}
+
+// Read32b reads a 32-byte array, such as a SHA-256 digest, from the Buffer.
+//
+// If an error occurred, Error() will return a non-nil error and the array
+// is all zeroes.
+func (l *Lexer) Read32b() [32]byte {
+	var a [32]byte
+	copy(a[:], l.consume(len(a)))
+	return a
+}
+
+// Write4 writes a 4-byte array to the Buffer.
+//
+// If an error occurred, Error() will return a non-nil error.
+func (l *Lexer) Write4(a [4]byte) {
+	l.WriteBytes(a[:])
+}
+
+// Write8b writes an 8-byte array to the Buffer.
+//
+// If an error occurred, Error() will return a non-nil error.
+func (l *Lexer) Write8b(a [8]byte) {
+	l.WriteBytes(a[:])
+}
+
+// Write16b writes a 16-byte array to the Buffer.
+//
+// If an error occurred, Error() will return a non-nil error.
+func (l *Lexer) Write16b(a [16]byte) {
+	l.WriteBytes(a[:])
+}
+
+// Write32b writes a 32-byte array to the Buffer.
+//
+// If an error occurred, Error() will return a non-nil error.
+func (l *Lexer) Write32b(a [32]byte) {
+	l.WriteBytes(a[:])
+}
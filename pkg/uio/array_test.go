@@ -0,0 +1,75 @@
+// Copyright 2018 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package uio
+
+import (
+	"errors"
+	"io"
+	"reflect"
+	"testing"
+)
+
+func TestArrays(t *testing.T) {
+	var (
+		a4  = [4]byte{0x7f, 'E', 'L', 'F'}
+		a8  = [8]byte{0x89, 'P', 'N', 'G', '\r', '\n', 0x1a, '\n'}
+		a16 [16]byte
+		a32 [32]byte
+	)
+	for i := range a16 {
+		a16[i] = byte(i)
+	}
+	for i := range a32 {
+		a32[i] = byte(0xff - i)
+	}
+
+	l := NewBigEndianBuffer(nil)
+	l.Write4(a4)
+	l.Write8b(a8)
+	l.Write16b(a16)
+	l.Write32b(a32)
+	if got, want := l.Len(), 4+8+16+32; got != want {
+		t.Fatalf("Len() = %d, want %d", got, want)
+	}
+
+	got4 := l.Read4()
+	got8 := l.Read8b()
+	got16 := l.Read16b()
+	got32 := l.Read32b()
+	if err := l.Error(); err != nil {
+		t.Fatalf("Error() = %v, want nil", err)
+	}
+
+	// The arrays must not alias the Buffer.
+	for i := range l.data {
+		l.data[i] = 0
+	}
+	for _, tt := range []struct {
+		name      string
+		got, want interface{}
+	}{
+		{"Read4", got4, a4},
+		{"Read8b", got8, a8},
+		{"Read16b", got16, a16},
+		{"Read32b", got32, a32},
+	} {
+		if !reflect.DeepEqual(tt.got, tt.want) {
+			t.Errorf("%s() = %#v, want %#v", tt.name, tt.got, tt.want)
+		}
+	}
+}
+
+func TestReadArrayShort(t *testing.T) {
+	l := NewBigEndianBuffer([]byte{1, 2, 3})
+	if got := l.Read4(); got != [4]byte{} {
+		t.Errorf("Read4() = %#v, want zeroes", got)
+	}
+	if err := l.Error(); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("Error() = %v, want %v", err, io.ErrUnexpectedEOF)
+	}
+	if l.Len() != 3 {
+		t.Errorf("Len() = %d, want 3", l.Len())
+	}
+}
@@ -0,0 +1,90 @@
+// Copyright 2018 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package uio
+
+import (
+	"errors"
+)
+
+// ErrInvalidBCD is set when a BCD value contains a nibble greater than 9.
+var ErrInvalidBCD = errors.New("invalid BCD digit")
+
+// fromBCD decodes the packed-BCD number v of the given number of digits.
+// Invalid nibbles are decoded as their values 10 to 15.
+func fromBCD(v uint64, digits int) (uint64, bool) {
+	var n, scale uint64 = 0, 1
+	valid := true
+	for i := 0; i < digits; i++ {
+		d := v & 0xf
+		if d > 9 {
+			valid = false
+		}
+		n += d * scale
+		scale *= 10
+		v >>= 4
+	}
+	return n, valid
+}
+
+// toBCD encodes n as a packed-BCD number.
+func toBCD(n uint64) uint64 {
+	var v uint64
+	for shift := uint(0); n != 0; shift += 4 {
+		v |= n % 10 << shift
+		n /= 10
+	}
+	return v
+}
+
+// ReadBCD8 reads a packed-BCD byte, e.g. 0x23, and returns its decimal
+// value, 23.
+//
+// If a nibble is greater than 9, it is decoded as its value 10 to 15, and
+// Error() will return ErrInvalidBCD.
+func (l *Lexer) ReadBCD8() uint8 {
+	n, ok := fromBCD(uint64(l.Read8()), 2)
+	if !ok {
+		l.setError(ErrInvalidBCD)
+	}
+	return uint8(n)
+}
+
+// ReadBCD16 reads a 16-bit packed-BCD value in the Lexer's byte order, e.g.
+// bcdUSB 0x0210, and returns its decimal value, 210.
+//
+// If a nibble is greater than 9, it is decoded as its value 10 to 15, and
+// Error() will return ErrInvalidBCD.
+func (l *Lexer) ReadBCD16() uint16 {
+	n, ok := fromBCD(uint64(l.Read16()), 4)
+	if !ok {
+		l.setError(ErrInvalidBCD)
+	}
+	return uint16(n)
+}
+
+// WriteBCD8 writes n, which must be at most 99, as a packed-BCD byte.
+//
+// If n is greater than 99, nothing is written and Error() will return
+// ErrOverflow.
+func (l *Lexer) WriteBCD8(n uint8) {
+	if n > 99 {
+		l.setError(ErrOverflow)
+		return
+	}
+	l.Write8(uint8(toBCD(uint64(n))))
+}
+
+// WriteBCD16 writes n, which must be at most 9999, as a 16-bit packed-BCD
+// value in the Lexer's byte order.
+//
+// If n is greater than 9999, nothing is written and Error() will return
+// ErrOverflow.
+func (l *Lexer) WriteBCD16(n uint16) {
+	if n > 9999 {
+		l.setError(ErrOverflow)
+		return
+	}
+	l.Write16(uint16(toBCD(uint64(n))))
+}
@@ -0,0 +1,67 @@
+// Copyright 2018 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package uio
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestBCD(t *testing.T) {
+	l := NewLittleEndianBuffer(nil)
+	l.WriteBCD8(0)
+	l.WriteBCD8(23)
+	l.WriteBCD8(99)
+	l.WriteBCD16(210)
+	l.WriteBCD16(9999)
+	if err := l.Error(); err != nil {
+		t.Fatalf("Error() = %v, want nil", err)
+	}
+	want := []byte{0x00, 0x23, 0x99, 0x10, 0x02, 0x99, 0x99}
+	if got := l.Data(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Data() = %#v, want %#v", got, want)
+	}
+
+	for _, want := range []uint8{0, 23, 99} {
+		if got := l.ReadBCD8(); got != want {
+			t.Errorf("ReadBCD8() = %d, want %d", got, want)
+		}
+	}
+	for _, want := range []uint16{210, 9999} {
+		if got := l.ReadBCD16(); got != want {
+			t.Errorf("ReadBCD16() = %d, want %d", got, want)
+		}
+	}
+	if err := l.Error(); err != nil {
+		t.Errorf("Error() = %v, want nil", err)
+	}
+}
+
+func TestBCDErrors(t *testing.T) {
+	l := NewBigEndianBuffer([]byte{0x1a, 0x00, 0xf0})
+	if got := l.ReadBCD8(); got != 20 {
+		t.Errorf("ReadBCD8() = %d, want 20", got)
+	}
+	if err := l.Error(); !errors.Is(err, ErrInvalidBCD) {
+		t.Errorf("ReadBCD8(0x1a): Error() = %v, want %v", err, ErrInvalidBCD)
+	}
+
+	l = NewBigEndianBuffer([]byte{0x00, 0xf0})
+	l.ReadBCD16()
+	if err := l.Error(); !errors.Is(err, ErrInvalidBCD) {
+		t.Errorf("ReadBCD16(0x00f0): Error() = %v, want %v", err, ErrInvalidBCD)
+	}
+
+	l = NewBigEndianBuffer(nil)
+	l.WriteBCD8(100)
+	l.WriteBCD16(10000)
+	if got := l.Errors(); len(got) != 2 || !errors.Is(got[0], ErrOverflow) || !errors.Is(got[1], ErrOverflow) {
+		t.Errorf("Errors() = %v, want two %v", got, ErrOverflow)
+	}
+	if l.Len() != 0 {
+		t.Errorf("Len() = %d, want 0", l.Len())
+	}
+}
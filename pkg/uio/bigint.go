@@ -0,0 +1,79 @@
+// Copyright 2018 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package uio
+
+import (
+	"math/big"
+)
+
+// ReadBigInt reads an n-byte unsigned big-endian integer, regardless of the
+// Lexer's byte order.
+//
+// If an error occurred, ReadBigInt returns nil and Error() will return a
+// non-nil error.
+func (l *Lexer) ReadBigInt(n int) *big.Int {
+	if n < 0 {
+		l.setError(ErrInvalidSize)
+		return nil
+	}
+	v := l.consume(n)
+	if v == nil {
+		return nil
+	}
+	return new(big.Int).SetBytes(v)
+}
+
+// WriteBigInt writes v as an n-byte unsigned big-endian integer, padded with
+// leading zeroes, regardless of the Lexer's byte order.
+//
+// If v is negative or does not fit in n bytes, nothing is written and
+// Error() will return ErrOverflow.
+func (l *Lexer) WriteBigInt(v *big.Int, n int) {
+	if n < 0 {
+		l.setError(ErrInvalidSize)
+		return
+	}
+	if v.Sign() < 0 || (v.BitLen()+7)/8 > n {
+		l.setError(ErrOverflow)
+		return
+	}
+	p := v.Bytes()
+	if b := l.append(n); b != nil {
+		copy(b[n-len(p):], p)
+	}
+}
+
+// ReadBigIntMinimal reads an unsigned big-endian integer prefixed by its
+// 16-bit length in bytes, as written by WriteBigIntMinimal. In strict mode,
+// leading zero bytes set ErrNonCanonical.
+//
+// If an error occurred, ReadBigIntMinimal returns nil and Error() will
+// return a non-nil error.
+func (l *Lexer) ReadBigIntMinimal() *big.Int {
+	errs := len(l.errs)
+	v := l.readLengthPrefixed(2)
+	if len(l.errs) > errs {
+		return nil
+	}
+	if l.strict && len(v) > 0 && v[0] == 0 {
+		l.setError(ErrNonCanonical)
+		return nil
+	}
+	return new(big.Int).SetBytes(v)
+}
+
+// WriteBigIntMinimal writes v as an unsigned big-endian integer of as few
+// bytes as possible, prefixed by its 16-bit length in bytes like a TPM2B
+// buffer. Zero is written as an empty integer.
+//
+// If v is negative or longer than 65535 bytes, nothing is written and
+// Error() will return ErrOverflow.
+func (l *Lexer) WriteBigIntMinimal(v *big.Int) {
+	if v.Sign() < 0 || (v.BitLen()+7)/8 > 0xffff {
+		l.setError(ErrOverflow)
+		return
+	}
+	l.writeLengthPrefixed(2, v.Bytes())
+}
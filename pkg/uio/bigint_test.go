@@ -0,0 +1,76 @@
+// Copyright 2018 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package uio
+
+import (
+	"errors"
+	"io"
+	"math/big"
+	"reflect"
+	"testing"
+)
+
+func TestBigInt(t *testing.T) {
+	v := new(big.Int).SetUint64(0x010203)
+
+	l := NewLittleEndianBuffer(nil)
+	l.WriteBigInt(v, 5)
+	l.WriteBigInt(new(big.Int), 2)
+	l.WriteBigIntMinimal(v)
+	l.WriteBigIntMinimal(new(big.Int))
+	if err := l.Error(); err != nil {
+		t.Fatalf("Error() = %v, want nil", err)
+	}
+	want := []byte{
+		0x00, 0x00, 0x01, 0x02, 0x03,
+		0x00, 0x00,
+		0x03, 0x00, 0x01, 0x02, 0x03,
+		0x00, 0x00,
+	}
+	if got := l.Data(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Data() = %#v, want %#v", got, want)
+	}
+
+	if got := l.ReadBigInt(5); got == nil || got.Cmp(v) != 0 {
+		t.Errorf("ReadBigInt(5) = %v, want %v", got, v)
+	}
+	if got := l.ReadBigInt(2); got == nil || got.Sign() != 0 {
+		t.Errorf("ReadBigInt(2) = %v, want 0", got)
+	}
+	if got := l.ReadBigIntMinimal(); got == nil || got.Cmp(v) != 0 {
+		t.Errorf("ReadBigIntMinimal() = %v, want %v", got, v)
+	}
+	if got := l.ReadBigIntMinimal(); got == nil || got.Sign() != 0 {
+		t.Errorf("ReadBigIntMinimal() = %v, want 0", got)
+	}
+	if err := l.Error(); err != nil {
+		t.Errorf("Error() = %v, want nil", err)
+	}
+	if got := l.ReadBigInt(1); got != nil {
+		t.Errorf("ReadBigInt(1) of empty Buffer = %v, want nil", got)
+	}
+	if err := l.Error(); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("Error() = %v, want %v", err, io.ErrUnexpectedEOF)
+	}
+}
+
+func TestBigIntOverflow(t *testing.T) {
+	l := NewBigEndianBuffer(nil)
+	l.WriteBigInt(new(big.Int).SetUint64(0x0100), 1)
+	l.WriteBigInt(big.NewInt(-1), 4)
+	l.WriteBigIntMinimal(big.NewInt(-1))
+	l.WriteBigIntMinimal(new(big.Int).Lsh(big.NewInt(1), 8*0xffff))
+	if got := l.Errors(); len(got) != 4 {
+		t.Errorf("Errors() = %v, want 4 errors", got)
+	}
+	for _, err := range l.Errors() {
+		if !errors.Is(err, ErrOverflow) {
+			t.Errorf("error = %v, want %v", err, ErrOverflow)
+		}
+	}
+	if l.Len() != 0 {
+		t.Errorf("Len() = %d, want 0", l.Len())
+	}
+}
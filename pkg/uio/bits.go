@@ -0,0 +1,199 @@
+// Copyright 2018 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package uio
+
+import (
+	"io"
+)
+
+// BitReader reads fields that are not byte-aligned from a Lexer.
+//
+// Bits are read MSB-first: the first bit read is the most significant bit of
+// the first byte, and multi-bit values are assembled most significant bit
+// first. This is the order used by most media formats.
+type BitReader struct {
+	l *Lexer
+
+	// cur holds the partially read byte.
+	cur byte
+
+	// nbits is the number of unread bits left in cur.
+	nbits uint
+}
+
+// NewBitReader returns a BitReader that reads from l.
+func NewBitReader(l *Lexer) *BitReader {
+	return &BitReader{l: l}
+}
+
+// ReadBits reads an n-bit value, for n in 1..64.
+//
+// Errors are also recorded in the underlying Lexer.
+func (r *BitReader) ReadBits(n int) (uint64, error) {
+	if n < 1 || n > 64 {
+		r.l.setError(ErrInvalidSize)
+		return 0, r.l.Error()
+	}
+	var v uint64
+	for left := uint(n); left > 0; {
+		if r.nbits == 0 {
+			b := r.l.consume(1)
+			if b == nil {
+				return 0, r.l.Error()
+			}
+			r.cur, r.nbits = b[0], 8
+		}
+		take := left
+		if take > r.nbits {
+			take = r.nbits
+		}
+		v = v<<take | uint64(r.cur>>(r.nbits-take))&(1<<take-1)
+		r.nbits -= take
+		left -= take
+	}
+	return v, nil
+}
+
+// Align discards the remaining bits of a partially read byte, so that the
+// next read starts on a byte boundary.
+func (r *BitReader) Align() {
+	r.nbits = 0
+}
+
+// BitWriter writes fields that are not byte-aligned to a Lexer.
+//
+// Bits are written MSB-first, matching BitReader.
+type BitWriter struct {
+	l *Lexer
+
+	// cur holds the partially written byte.
+	cur byte
+
+	// nbits is the number of bits already written to cur.
+	nbits uint
+}
+
+// NewBitWriter returns a BitWriter that writes to l.
+func NewBitWriter(l *Lexer) *BitWriter {
+	return &BitWriter{l: l}
+}
+
+// WriteBits writes the low n bits of v, for n in 1..64.
+//
+// If n is out of range, Error() on the underlying Lexer will return
+// ErrInvalidSize. If v does not fit in n bits, it will return ErrOverflow.
+// In both cases nothing is written.
+func (w *BitWriter) WriteBits(v uint64, n int) {
+	if n < 1 || n > 64 {
+		w.l.setError(ErrInvalidSize)
+		return
+	}
+	if n < 64 && v>>uint(n) != 0 {
+		w.l.setError(ErrOverflow)
+		return
+	}
+	for left := uint(n); left > 0; {
+		take := 8 - w.nbits
+		if take > left {
+			take = left
+		}
+		bits := byte(v>>(left-take)) & (1<<take - 1)
+		w.cur |= bits << (8 - w.nbits - take)
+		w.nbits += take
+		left -= take
+		if w.nbits == 8 {
+			w.l.Write8(w.cur)
+			w.cur, w.nbits = 0, 0
+		}
+	}
+}
+
+// Align pads a partially written byte with zero bits and writes it, so that
+// the next write starts on a byte boundary.
+func (w *BitWriter) Align() {
+	if w.nbits > 0 {
+		w.l.Write8(w.cur)
+		w.cur, w.nbits = 0, 0
+	}
+}
+
+// Flush writes any partially written byte, padded with zero bits.
+//
+// Flush must be called after the last WriteBits.
+func (w *BitWriter) Flush() {
+	w.Align()
+}
+
+// packedBytes returns the number of bytes taken by fields of the given bit
+// widths, and sets the error if a width is not in 1..64.
+func (l *Lexer) packedBytes(widths []int) (int, bool) {
+	bits := 0
+	for _, w := range widths {
+		if w < 1 || w > 64 {
+			l.setError(ErrInvalidSize)
+			return 0, false
+		}
+		bits += w
+	}
+	return (bits + 7) / 8, true
+}
+
+// ReadPackedFields reads MSB-first bit fields of the given widths, as read
+// by BitReader, and returns their values, e.g. for an IPv4 header's version
+// and IHL:
+//
+//	f := l.ReadPackedFields(4, 4)
+//
+// The fields need not add up to whole bytes; the unused low bits of the
+// last byte are consumed and ignored.
+//
+// If a width is not in 1..64, nothing is read and Error() will return
+// ErrInvalidSize. If the Buffer is too short, nothing is read and Error()
+// will return io.ErrUnexpectedEOF.
+func (l *Lexer) ReadPackedFields(widths ...int) []uint64 {
+	n, ok := l.packedBytes(widths)
+	if !ok {
+		return nil
+	}
+	if !l.Has(n) {
+		l.setError(io.ErrUnexpectedEOF)
+		return nil
+	}
+	r := NewBitReader(l)
+	values := make([]uint64, len(widths))
+	for i, w := range widths {
+		values[i], _ = r.ReadBits(w)
+	}
+	return values
+}
+
+// WritePackedFields writes each of values as an MSB-first bit field of the
+// corresponding width, as written by BitWriter, padding the last byte with
+// zero bits.
+//
+// If values and widths differ in length, or a width is not in 1..64,
+// nothing is written and Error() will return ErrInvalidSize. If a value does
+// not fit in its width, nothing is written and Error() will return
+// ErrOverflow.
+func (l *Lexer) WritePackedFields(values []uint64, widths []int) {
+	if len(values) != len(widths) {
+		l.setError(ErrInvalidSize)
+		return
+	}
+	if _, ok := l.packedBytes(widths); !ok {
+		return
+	}
+	for i, w := range widths {
+		if w < 64 && values[i]>>uint(w) != 0 {
+			l.setError(ErrOverflow)
+			return
+		}
+	}
+	w := NewBitWriter(l)
+	for i, v := range values {
+		w.WriteBits(v, widths[i])
+	}
+	w.Flush()
+}
@@ -0,0 +1,196 @@
+// Copyright 2018 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package uio
+
+import (
+	"errors"
+	"io"
+	"reflect"
+	"testing"
+)
+
+func TestBitReader(t *testing.T) {
+	l := NewBigEndianBuffer([]byte{0xb5, 0x3c, 0xff, 0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08, 0x80})
+	r := NewBitReader(l)
+	for i, tt := range []struct {
+		n    int
+		want uint64
+	}{
+		{n: 1, want: 0x1},
+		{n: 3, want: 0x3},
+		{n: 6, want: 0x14},
+		{n: 6, want: 0x3c},
+	} {
+		got, err := r.ReadBits(tt.n)
+		if err != nil {
+			t.Fatalf("#%d: ReadBits(%d) = %v", i, tt.n, err)
+		}
+		if got != tt.want {
+			t.Errorf("#%d: ReadBits(%d) = %#x, want %#x", i, tt.n, got, tt.want)
+		}
+	}
+	if got, err := r.ReadBits(4); err != nil || got != 0xf {
+		t.Errorf("ReadBits(4) = %#x, %v, want 0xf, nil", got, err)
+	}
+	r.Align()
+	if got, err := r.ReadBits(64); err != nil || got != 0x0102030405060708 {
+		t.Errorf("ReadBits(64) = %#x, %v, want 0x0102030405060708, nil", got, err)
+	}
+	if got, err := r.ReadBits(1); err != nil || got != 1 {
+		t.Errorf("ReadBits(1) = %#x, %v, want 1, nil", got, err)
+	}
+	if _, err := r.ReadBits(8); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("ReadBits(8) past the end = %v, want %v", err, io.ErrUnexpectedEOF)
+	}
+	if err := l.Error(); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("Lexer.Error() = %v, want %v", err, io.ErrUnexpectedEOF)
+	}
+
+	for _, n := range []int{0, 65} {
+		r := NewBitReader(NewBigEndianBuffer(make([]byte, 16)))
+		if _, err := r.ReadBits(n); !errors.Is(err, ErrInvalidSize) {
+			t.Errorf("ReadBits(%d) = %v, want %v", n, err, ErrInvalidSize)
+		}
+	}
+}
+
+func TestBitWriter(t *testing.T) {
+	l := NewBigEndianBuffer(nil)
+	w := NewBitWriter(l)
+	w.WriteBits(0x1, 1)
+	w.WriteBits(0x3, 3)
+	w.WriteBits(0x14, 6)
+	w.WriteBits(0x3c, 6)
+	w.WriteBits(0xf, 4)
+	w.Align()
+	w.WriteBits(0x0102030405060708, 64)
+	w.WriteBits(0x1, 1)
+	w.Flush()
+
+	want := []byte{0xb5, 0x3c, 0xf0, 0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08, 0x80}
+	if got := l.Data(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Data() = %#v, want %#v", got, want)
+	}
+	if err := l.Error(); err != nil {
+		t.Errorf("Error() = %v, want nil", err)
+	}
+}
+
+func TestBitRoundTrip(t *testing.T) {
+	widths := []int{1, 7, 13, 64, 2, 33, 5}
+	values := []uint64{1, 0x55, 0x1abc, 0xdeadbeefcafef00d, 2, 0x1ffffffff, 0}
+
+	l := NewLittleEndianBuffer(nil)
+	w := NewBitWriter(l)
+	for i := range widths {
+		w.WriteBits(values[i], widths[i])
+	}
+	w.Flush()
+
+	r := NewBitReader(l)
+	for i := range widths {
+		got, err := r.ReadBits(widths[i])
+		if err != nil {
+			t.Fatalf("ReadBits(%d) = %v", widths[i], err)
+		}
+		if got != values[i] {
+			t.Errorf("ReadBits(%d) = %#x, want %#x", widths[i], got, values[i])
+		}
+	}
+}
+
+func TestBitWriterErrors(t *testing.T) {
+	for i, tt := range []struct {
+		v   uint64
+		n   int
+		err error
+	}{
+		{v: 0, n: 0, err: ErrInvalidSize},
+		{v: 0, n: 65, err: ErrInvalidSize},
+		{v: 0x10, n: 4, err: ErrOverflow},
+	} {
+		l := NewBigEndianBuffer(nil)
+		w := NewBitWriter(l)
+		w.WriteBits(tt.v, tt.n)
+		w.Flush()
+		if err := l.Error(); !errors.Is(err, tt.err) {
+			t.Errorf("#%d: WriteBits(%#x, %d): Error() = %v, want %v", i, tt.v, tt.n, err, tt.err)
+		}
+		if l.Len() != 0 {
+			t.Errorf("#%d: WriteBits(%#x, %d) wrote %d bytes, want 0", i, tt.v, tt.n, l.Len())
+		}
+	}
+}
+
+func TestPackedFields(t *testing.T) {
+	for i, tt := range []struct {
+		widths []int
+		values []uint64
+		want   []byte
+	}{
+		// IPv4 version and IHL.
+		{widths: []int{4, 4}, values: []uint64{4, 5}, want: []byte{0x45}},
+		// 802.1Q tag control information: PCP, DEI, VID.
+		{widths: []int{3, 1, 12}, values: []uint64{5, 0, 0x064}, want: []byte{0xa0, 0x64}},
+		{widths: []int{1, 2}, values: []uint64{1, 3}, want: []byte{0xe0}},
+		{widths: []int{64, 4}, values: []uint64{1 << 63, 0xf}, want: []byte{0x80, 0, 0, 0, 0, 0, 0, 0, 0xf0}},
+	} {
+		l := NewBigEndianBuffer(nil)
+		l.WritePackedFields(tt.values, tt.widths)
+		if got := l.Data(); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("#%d: WritePackedFields() = %#v, want %#v", i, got, tt.want)
+		}
+		if got := l.ReadPackedFields(tt.widths...); !reflect.DeepEqual(got, tt.values) {
+			t.Errorf("#%d: ReadPackedFields() = %#v, want %#v", i, got, tt.values)
+		}
+		if err := l.Error(); err != nil {
+			t.Errorf("#%d: Error() = %v, want nil", i, err)
+		}
+		if l.Len() != 0 {
+			t.Errorf("#%d: Len() = %d, want 0", i, l.Len())
+		}
+	}
+}
+
+func TestPackedFieldsErrors(t *testing.T) {
+	for i, tt := range []struct {
+		widths []int
+		values []uint64
+		err    error
+	}{
+		{widths: []int{4, 4}, values: []uint64{4}, err: ErrInvalidSize},
+		{widths: []int{4, 0}, values: []uint64{4, 0}, err: ErrInvalidSize},
+		{widths: []int{65}, values: []uint64{0}, err: ErrInvalidSize},
+		{widths: []int{4, 4}, values: []uint64{4, 0x10}, err: ErrOverflow},
+	} {
+		l := NewBigEndianBuffer(nil)
+		l.WritePackedFields(tt.values, tt.widths)
+		if err := l.Error(); !errors.Is(err, tt.err) {
+			t.Errorf("#%d: WritePackedFields(): Error() = %v, want %v", i, err, tt.err)
+		}
+		if l.Len() != 0 {
+			t.Errorf("#%d: WritePackedFields() wrote %d bytes, want 0", i, l.Len())
+		}
+	}
+
+	for i, tt := range []struct {
+		widths []int
+		err    error
+	}{
+		{widths: []int{4, 0}, err: ErrInvalidSize},
+		{widths: []int{8, 9}, err: io.ErrUnexpectedEOF},
+	} {
+		l := NewBigEndianBuffer([]byte{0x01, 0x02})
+		if got := l.ReadPackedFields(tt.widths...); got != nil {
+			t.Errorf("#%d: ReadPackedFields() = %#v, want nil", i, got)
+		}
+		if err := l.Error(); !errors.Is(err, tt.err) {
+			t.Errorf("#%d: ReadPackedFields(): Error() = %v, want %v", i, err, tt.err)
+		}
+		if l.Len() != 2 {
+			t.Errorf("#%d: Len() = %d, want 2", i, l.Len())
+		}
+	}
+}
@@ -0,0 +1,1939 @@
+// Copyright 2018 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package uio
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"math"
+)
+
+// ErrOverflow is set when a value does not fit in the field it is written to.
+var ErrOverflow = errors.New("value does not fit in field")
+
+// ErrInvalidSize is set when a field size is outside of the supported range.
+var ErrInvalidSize = errors.New("invalid field size")
+
+// ErrTooLong is set when a variable-length field exceeds its maximum length.
+var ErrTooLong = errors.New("field exceeds maximum length")
+
+// ErrInvalidOffset is set when an offset lies before the current position.
+var ErrInvalidOffset = errors.New("invalid offset")
+
+// ErrUnreadBytes is set when there is data left to read in the buffer.
+var ErrUnreadBytes = errors.New("buffer contains unread bytes")
+
+const maxInt = int(^uint(0) >> 1)
+
+// FieldError is the error set by a Lexer. It records where in the Buffer the
+// underlying error Err occurred.
+//
+// Use errors.Is or errors.As to check for a particular underlying error.
+type FieldError struct {
+	// Field is the name of the field being read or written, as set by
+	// Lexer.Field, prefixed by the path set by Lexer.PushField and
+	// Lexer.PushIndex. It may be empty.
+	Field string
+
+	// Offset is the read position at the time of the error.
+	Offset int
+
+	// Err is the underlying error.
+	Err error
+}
+
+// Error implements error.
+func (e *FieldError) Error() string {
+	if e.Field != "" {
+		return fmt.Sprintf("error in field %q at offset %d: %v", e.Field, e.Offset, e.Err)
+	}
+	return fmt.Sprintf("error at offset %d: %v", e.Offset, e.Err)
+}
+
+// Unwrap returns the underlying error.
+func (e *FieldError) Unwrap() error {
+	return e.Err
+}
+
+// Marshaler is the interface implemented by an object that can marshal itself
+// into binary form.
+//
+// Marshal appends data to the buffer b.
+type Marshaler interface {
+	Marshal(l *Lexer)
+}
+
+// Unmarshaler is the interface implemented by an object that can unmarshal a
+// binary representation of itself.
+//
+// Unmarshal consumes data from the buffer b.
+type Unmarshaler interface {
+	Unmarshal(l *Lexer) error
+}
+
+// ToBigEndian marshals m to big endian byte order.
+func ToBigEndian(m Marshaler) []byte {
+	l := NewBigEndianBuffer(nil)
+	m.Marshal(l)
+	return l.Data()
+}
+
+// FromBigEndian unmarshals b into obj in big endian byte order.
+func FromBigEndian(obj Unmarshaler, b []byte) error {
+	l := NewBigEndianBuffer(b)
+	return obj.Unmarshal(l)
+}
+
+// ToLittleEndian marshals m to little endian byte order.
+func ToLittleEndian(m Marshaler) []byte {
+	l := NewLittleEndianBuffer(nil)
+	m.Marshal(l)
+	return l.Data()
+}
+
+// FromLittleEndian unmarshals b into obj in little endian byte order.
+func FromLittleEndian(obj Unmarshaler, b []byte) error {
+	l := NewLittleEndianBuffer(b)
+	return obj.Unmarshal(l)
+}
+
+// SizeOf returns the number of bytes m marshals to, e.g. to write an outer
+// length before m itself.
+//
+// m is marshaled in big endian byte order to a Lexer returned by
+// NewCountingLexer.
+func SizeOf(m Marshaler) int {
+	l := NewCountingLexer(binary.BigEndian)
+	m.Marshal(l)
+	return l.Len()
+}
+
+// MarshalAll marshals each of ms to l, in order.
+func MarshalAll(l *Lexer, ms ...Marshaler) {
+	for _, m := range ms {
+		m.Marshal(l)
+	}
+}
+
+// UnmarshalAll unmarshals each of ums from l, in order.
+//
+// It stops at the first error returned by an Unmarshal call or set in l, and
+// returns it. In strict mode, it returns ErrUnreadBytes if there is data left
+// after the last one.
+func UnmarshalAll(l *Lexer, ums ...Unmarshaler) error {
+	for _, u := range ums {
+		if err := u.Unmarshal(l); err != nil {
+			return err
+		}
+		if err := l.Error(); err != nil {
+			return err
+		}
+	}
+	if l.strict {
+		l.AssertFinished()
+		return l.Error()
+	}
+	return nil
+}
+
+// WriteBinaryMarshaler writes the binary form of m, as returned by its
+// MarshalBinary method, to l.
+//
+// If MarshalBinary fails, nothing is written and Error() will return its
+// error.
+func WriteBinaryMarshaler(l *Lexer, m encoding.BinaryMarshaler) {
+	p, err := m.MarshalBinary()
+	if err != nil {
+		l.setError(err)
+		return
+	}
+	l.WriteBytes(p)
+}
+
+// ReadBinaryUnmarshaler consumes the next n bytes of l and passes them to
+// u's UnmarshalBinary method.
+//
+// If UnmarshalBinary fails, the n bytes are still consumed and Error() will
+// return its error.
+func ReadBinaryUnmarshaler(l *Lexer, n int, u encoding.BinaryUnmarshaler) {
+	v := l.consume(n)
+	if v == nil {
+		return
+	}
+	if err := u.UnmarshalBinary(v); err != nil {
+		l.setError(err)
+	}
+}
+
+// Buffer implements functions to manipulate byte slices in a zero-copy way.
+//
+// Consumed bytes are kept in the underlying array so that a reader can
+// rewind to an earlier position. As a consequence, the memory they occupy is
+// not released until the Buffer itself is no longer referenced.
+type Buffer struct {
+	// data is the underlying data, including bytes already consumed.
+	data []byte
+
+	// off is the read position in data; data[off:] is unconsumed.
+	off int
+
+	// r, if not nil, is read from whenever more data is needed.
+	r io.Reader
+
+	// rerr is the error r last returned.
+	rerr error
+
+	// counting Buffers keep no data: writes only add to n, and are made
+	// to scratch.
+	counting bool
+	n        int
+	scratch  []byte
+
+	// ring, if not 0, is the capacity of a ring Buffer.
+	ring int
+}
+
+// NewBuffer consumes b for marshaling or unmarshaling.
+func NewBuffer(b []byte) *Buffer {
+	return &Buffer{data: b}
+}
+
+// NewBufferSize returns an empty Buffer with room for n bytes to be written
+// before it has to grow.
+func NewBufferSize(n int) *Buffer {
+	return NewBuffer(make([]byte, 0, n))
+}
+
+// Grow makes sure that at least n more bytes can be written to the Buffer
+// without reallocating. For a ring Buffer, n is limited to its capacity.
+func (b *Buffer) Grow(n int) {
+	if b.ring > 0 && n > b.ring {
+		n = b.ring
+	}
+	b.grow(n)
+}
+
+// Reset empties the Buffer and rewinds its read position, but keeps the
+// underlying array for reuse by later writes.
+func (b *Buffer) Reset() {
+	b.data = b.data[:0]
+	b.off = 0
+	b.n = 0
+	b.r = nil
+	b.rerr = nil
+}
+
+// Truncate discards all but the first n unconsumed bytes of the Buffer,
+// keeping the underlying array for reuse by later writes.
+//
+// If n is negative or greater than Len(), nothing is discarded and Truncate
+// returns ErrInvalidSize.
+//
+// Lexer's Truncate method shadows this one, and sets the error instead.
+func (b *Buffer) Truncate(n int) error {
+	if n < 0 || n > b.Len() {
+		return ErrInvalidSize
+	}
+	if b.counting {
+		b.n = n
+		return nil
+	}
+	b.data = b.data[:b.off+n]
+	return nil
+}
+
+// Clone returns an independent copy of the Buffer, with the same read
+// position. Later reads and writes on either do not affect the other.
+//
+// Consumed bytes are copied too, so that positions returned by Tell or
+// Lexer.Mark are also valid in the clone. For a Buffer backed by an
+// io.Reader, the clone only holds the bytes already read from it.
+func (b *Buffer) Clone() *Buffer {
+	return &Buffer{
+		data:     append([]byte(nil), b.data...),
+		off:      b.off,
+		counting: b.counting,
+		n:        b.n,
+		ring:     b.ring,
+	}
+}
+
+// WriteN appends n bytes to the Buffer and returns a slice pointing to the
+// newly appended bytes.
+//
+// If n is negative, or the Buffer would grow beyond the largest int, nothing
+// is appended and WriteN returns nil.
+func (b *Buffer) WriteN(n int) []byte {
+	if n < 0 || n > maxInt-b.size() {
+		return nil
+	}
+	if b.ring > 0 {
+		b.dropOldest(len(b.data) + n - b.ring)
+	}
+	if b.counting {
+		b.n += n
+		if cap(b.scratch) < n {
+			b.scratch = make([]byte, n)
+		}
+		s := b.scratch[:n]
+		for i := range s {
+			s[i] = 0
+		}
+		return s
+	}
+	b.data = append(b.data, make([]byte, n)...)
+	return b.data[len(b.data)-n:]
+}
+
+// ReadN consumes n bytes from the Buffer. It returns nil and
+// io.ErrUnexpectedEOF if there aren't enough bytes left, or ErrInvalidSize
+// if n is negative.
+//
+// The returned slice aliases the Buffer's backing array, without copying:
+// it changes if the bytes are later changed in place, e.g. by WriteAt, and
+// must not be kept after the Buffer is Reset or reused. Use SafeReadN for a
+// copy.
+func (b *Buffer) ReadN(n int) ([]byte, error) {
+	if n < 0 {
+		return nil, ErrInvalidSize
+	}
+	if !b.Has(n) {
+		if b.rerr != nil && b.rerr != io.EOF {
+			return nil, b.rerr
+		}
+		return nil, io.ErrUnexpectedEOF
+	}
+	rval := b.data[b.off : b.off+n]
+	b.off += n
+	return rval, nil
+}
+
+// SafeReadN is like ReadN, but returns a copy of the n bytes, which can be
+// kept regardless of what later happens to the Buffer.
+func (b *Buffer) SafeReadN(n int) ([]byte, error) {
+	v, err := b.ReadN(n)
+	if err != nil {
+		return nil, err
+	}
+	p := make([]byte, n)
+	copy(p, v)
+	return p, nil
+}
+
+// Tell returns the number of bytes consumed since the Buffer was created.
+//
+// Writes do not change the position, since they append to the end of the
+// Buffer.
+func (b *Buffer) Tell() int {
+	return b.off
+}
+
+// ReadAt implements io.ReaderAt. It copies the bytes at offset off from the
+// start of the Buffer into p, including bytes that were already consumed,
+// without moving the read position.
+func (b *Buffer) ReadAt(p []byte, off int64) (int, error) {
+	if off < 0 || off > int64(maxInt-len(p)) {
+		return 0, ErrInvalidOffset
+	}
+	b.fill(int(off) + len(p) - b.off)
+	if off >= int64(len(b.data)) {
+		return 0, io.EOF
+	}
+	n := copy(p, b.data[off:])
+	if n < len(p) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+// WriteAt implements io.WriterAt. It overwrites the bytes at offset off from
+// the start of the Buffer with p, without moving the read position.
+//
+// Writing past the end of the Buffer grows it, and any gap between the old
+// end and off is filled with zeroes.
+func (b *Buffer) WriteAt(p []byte, off int64) (int, error) {
+	if off < 0 || off > int64(maxInt-len(p)) {
+		return 0, ErrInvalidOffset
+	}
+	end := int(off) + len(p)
+	if b.counting {
+		if end > b.n {
+			b.n = end
+		}
+		return len(p), nil
+	}
+	b.fill(end - b.off)
+	if end > len(b.data) {
+		b.WriteN(end - len(b.data))
+		if dropped := end - len(b.data); dropped > 0 {
+			// A ring Buffer dropped its oldest bytes to grow.
+			n := len(p)
+			if int(off) < dropped {
+				p = p[dropped-int(off):]
+			}
+			copy(b.data[len(b.data)-len(p):], p)
+			return n, nil
+		}
+	}
+	return copy(b.data[off:], p), nil
+}
+
+// Seek implements io.Seeker. It moves the read position to offset, relative
+// to the start of the Buffer, the read position or the end of the Buffer
+// according to whence, and returns the new position.
+//
+// Positions before the start or after the end of the Buffer are not allowed
+// and return ErrInvalidOffset. For a Buffer backed by an io.Reader, seeking
+// forwards or relative to the end reads from it as needed.
+func (b *Buffer) Seek(offset int64, whence int) (int64, error) {
+	var pos int64
+	switch whence {
+	case io.SeekStart:
+		pos = offset
+	case io.SeekCurrent:
+		pos = int64(b.off) + offset
+	case io.SeekEnd:
+		b.fill(maxInt)
+		pos = int64(len(b.data)) + offset
+	default:
+		return 0, ErrInvalidOffset
+	}
+	if pos < 0 || pos > int64(maxInt) {
+		return 0, ErrInvalidOffset
+	}
+	b.fill(int(pos) - b.off)
+	if pos > int64(len(b.data)) {
+		return 0, ErrInvalidOffset
+	}
+	b.off = int(pos)
+	return pos, nil
+}
+
+// Data is unconsumed data remaining in the Buffer.
+//
+// The returned slice aliases the Buffer without copying: changing its bytes
+// changes the Buffer's, and later writes, Rewind or Reset may change it in
+// turn. Use Bytes for a copy.
+//
+// For a Buffer backed by an io.Reader, only bytes already read from it are
+// returned.
+func (b *Buffer) Data() []byte {
+	return b.data[b.off:]
+}
+
+// Bytes returns a copy of the unconsumed data remaining in the Buffer, which
+// can be changed or kept regardless of what later happens to the Buffer.
+//
+// Unlike bytes.Buffer.Bytes, it does not alias the Buffer; use Data for
+// that.
+func (b *Buffer) Bytes() []byte {
+	d := b.Data()
+	p := make([]byte, len(d))
+	copy(p, d)
+	return p
+}
+
+// Has returns true if n bytes are available.
+//
+// For a Buffer backed by an io.Reader, Has reads from it until n bytes are
+// available or it returns an error.
+func (b *Buffer) Has(n int) bool {
+	b.fill(n)
+	return len(b.data)-b.off >= n
+}
+
+// Len returns the length of the remaining bytes. For a Buffer of a Lexer
+// returned by NewCountingLexer, it is the number of bytes written.
+//
+// For a Buffer backed by an io.Reader, only bytes already read from it are
+// counted.
+func (b *Buffer) Len() int {
+	if b.counting {
+		return b.n
+	}
+	return len(b.data) - b.off
+}
+
+// size returns the number of bytes written to the Buffer, including
+// consumed bytes.
+func (b *Buffer) size() int {
+	if b.counting {
+		return b.n
+	}
+	return len(b.data)
+}
+
+// Append appends the unconsumed bytes of other to the Buffer, without
+// consuming them from other. For an other backed by an io.Reader, all of it
+// is read first.
+//
+// Lexer's Append method shadows this one; use Lexer.WriteBuffer instead.
+func (b *Buffer) Append(other *Buffer) {
+	other.fill(maxInt)
+	d := other.Data()
+	copy(b.WriteN(len(d)), d)
+}
+
+// newCountingBuffer returns a Buffer that only counts the bytes written to
+// it.
+func newCountingBuffer() *Buffer {
+	return &Buffer{counting: true}
+}
+
+// Cap returns the available capacity.
+func (b *Buffer) Cap() int {
+	return cap(b.data) - b.off
+}
+
+// WriteTo implements io.WriterTo. It writes the unconsumed data to w and
+// consumes whatever was written.
+func (b *Buffer) WriteTo(w io.Writer) (int64, error) {
+	b.fill(maxInt)
+	d := b.Data()
+	n, err := w.Write(d)
+	b.off += n
+	if err == nil && n < len(d) {
+		err = io.ErrShortWrite
+	}
+	return int64(n), err
+}
+
+// minRead is the minimum free space ReadFrom passes to Read.
+const minRead = 512
+
+// grow makes sure there is room to append at least n bytes without
+// reallocating.
+func (b *Buffer) grow(n int) {
+	if cap(b.data)-len(b.data) < n {
+		d := make([]byte, len(b.data), 2*cap(b.data)+n)
+		copy(d, b.data)
+		b.data = d
+	}
+}
+
+// ReadFrom implements io.ReaderFrom. It appends data read from r until EOF.
+// A ring Buffer drops its oldest bytes as it reads, keeping the last ones.
+func (b *Buffer) ReadFrom(r io.Reader) (int64, error) {
+	var total int64
+	for {
+		b.grow(minRead)
+		n, err := r.Read(b.data[len(b.data):cap(b.data)])
+		b.data = b.data[:len(b.data)+n]
+		if b.ring > 0 {
+			b.dropOldest(len(b.data) - b.ring)
+		}
+		total += int64(n)
+		if err == io.EOF {
+			return total, nil
+		}
+		if err != nil {
+			return total, err
+		}
+	}
+}
+
+// String returns a hex dump of the unconsumed data, in the format of
+// hex.Dump, without consuming it.
+func (b *Buffer) String() string {
+	return hex.Dump(b.Data())
+}
+
+// Equal returns true if the unconsumed data is equal to other.
+func (b *Buffer) Equal(other []byte) bool {
+	return bytes.Equal(b.Data(), other)
+}
+
+// Diff compares the unconsumed data to other. If they differ, it returns the
+// offset of the first differing byte, which is the length of the shorter
+// one if it is a prefix of the other.
+func (b *Buffer) Diff(other []byte) (offset int, equal bool) {
+	d := b.Data()
+	for i := 0; i < len(d) && i < len(other); i++ {
+		if d[i] != other[i] {
+			return i, false
+		}
+	}
+	if len(d) != len(other) {
+		if len(d) < len(other) {
+			return len(d), false
+		}
+		return len(other), false
+	}
+	return 0, true
+}
+
+// Lexer is a convenient encoder/decoder for buffers.
+//
+// Use:
+//
+//	func (s *something) Unmarshal(l *Lexer) error {
+//	  s.Foo = l.Read8()
+//	  s.Bar = l.Read8()
+//	  s.Baz = l.Read16()
+//	  return l.Error()
+//	}
+type Lexer struct {
+	*Buffer
+
+	// order is the byte order to write in / read in.
+	order binary.ByteOrder
+
+	// err is the first error encountered while reading or writing.
+	err error
+
+	// errs is every error encountered while reading or writing.
+	errs []error
+
+	// field is the name of the current field, used to describe errors.
+	field string
+
+	// path is the stack of enclosing fields, used to describe errors.
+	path []pathElem
+
+	// maxAlloc is the largest number of bytes a single read may allocate,
+	// or 0 for no limit.
+	maxAlloc int
+
+	// maxSize is the largest size the Buffer may grow to by writes, or 0
+	// for no limit.
+	maxSize int
+
+	// strict enables the checks described by SetStrict.
+	strict bool
+
+	// tap, if not nil, is fed every byte consumed or appended.
+	tap hash.Hash
+
+	// tapped is the write position up to which appended bytes have been
+	// fed to tap.
+	tapped int
+
+	// sink, if not nil, is where MarshalTo flushes full chunks.
+	sink *chunkSink
+}
+
+// NewLexer returns a new coder for buffers.
+func NewLexer(b *Buffer, order binary.ByteOrder) *Lexer {
+	return &Lexer{
+		Buffer: b,
+		order:  order,
+	}
+}
+
+// NewLittleEndianBuffer returns a new little endian coder for a new buffer.
+func NewLittleEndianBuffer(b []byte) *Lexer {
+	return NewLexer(NewBuffer(b), binary.LittleEndian)
+}
+
+// NewBigEndianBuffer returns a new big endian coder for a new buffer.
+func NewBigEndianBuffer(b []byte) *Lexer {
+	return NewLexer(NewBuffer(b), binary.BigEndian)
+}
+
+// NewCountingLexer returns a Lexer that only counts the bytes written to it,
+// without keeping them, to measure the encoded size of a message:
+//
+//	c := uio.NewCountingLexer(binary.BigEndian)
+//	m.Marshal(c)
+//	l := uio.NewBigEndianBufferSize(c.Len())
+//
+// Len() reports the number of bytes written. No buffer for them is allocated
+// beyond scratch space for the largest single write. Writes work as usual,
+// including PlaceLength16 and friends, but Data() and RegionSince return no
+// data and reads fail with io.ErrUnexpectedEOF.
+func NewCountingLexer(order binary.ByteOrder) *Lexer {
+	return NewLexer(newCountingBuffer(), order)
+}
+
+// NewLittleEndianBufferSize returns a new little endian coder for an empty
+// buffer with room for n bytes.
+func NewLittleEndianBufferSize(n int) *Lexer {
+	return NewLexer(NewBufferSize(n), binary.LittleEndian)
+}
+
+// NewBigEndianBufferSize returns a new big endian coder for an empty buffer
+// with room for n bytes.
+func NewBigEndianBufferSize(n int) *Lexer {
+	return NewLexer(NewBufferSize(n), binary.BigEndian)
+}
+
+// Clone returns an independent copy of l, with a clone of its Buffer and
+// the same byte order, field path and errors. The clone is not tapped.
+//
+// It can be used to try decoding a message one way while keeping l for a
+// retry:
+//
+//	try := l.Clone()
+//	if err := v.Unmarshal(try); err != nil {
+//		// l is unaffected.
+//	}
+func (l *Lexer) Clone() *Lexer {
+	c := *l
+	c.Buffer = l.Buffer.Clone()
+	c.errs = append([]error(nil), l.errs...)
+	c.path = append([]pathElem(nil), l.path...)
+	c.tap = nil
+	c.sink = nil
+	return &c
+}
+
+// Order returns the byte order the Lexer reads and writes in.
+func (l *Lexer) Order() binary.ByteOrder {
+	return l.order
+}
+
+// SetOrder changes the byte order for all subsequent reads and writes.
+func (l *Lexer) SetOrder(order binary.ByteOrder) {
+	l.order = order
+}
+
+// littleEndian returns true if order stores the least significant byte first.
+func littleEndian(order binary.ByteOrder) bool {
+	return order.Uint16([]byte{0x01, 0x00}) == 0x0001
+}
+
+// SetMaxAlloc limits the number of bytes that a single read may allocate to
+// n, or removes the limit if n is 0.
+//
+// Parsers of untrusted input should set it, so that a bogus length field
+// cannot make a read such as CopyN, ReadAll, Read16N or ReadLengthPrefixed32
+// allocate gigabytes. Reads that would exceed the limit consume nothing, and
+// Error() will return ErrTooLong.
+func (l *Lexer) SetMaxAlloc(n int) {
+	l.maxAlloc = n
+}
+
+// SetMaxSize limits writes to growing the Buffer, including consumed bytes,
+// to at most n bytes, or removes the limit if n is 0.
+//
+// Serializers whose output size depends on untrusted input should set it,
+// so that e.g. WriteZeroes with a bogus count fails instead of allocating
+// gigabytes. Writes that would exceed the limit write nothing, and Error()
+// will return ErrTooLong. Without a limit, a write too large to allocate
+// panics, as with bytes.Buffer.
+func (l *Lexer) SetMaxSize(n int) {
+	l.maxSize = n
+}
+
+// checkAlloc returns true if a read may allocate n bytes, and sets the error
+// otherwise: ErrInvalidSize if n is negative, e.g. a 32-bit length converted
+// to int on a 32-bit platform, or ErrTooLong if n exceeds the limit set by
+// SetMaxAlloc.
+func (l *Lexer) checkAlloc(n int) bool {
+	if n < 0 {
+		l.setError(ErrInvalidSize)
+		return false
+	}
+	return l.checkAlloc64(uint64(n))
+}
+
+// checkAlloc64 is like checkAlloc, for a length read from the data that may
+// not fit in an int. Callers check it against maxInt afterwards, so that an
+// oversized length is reported as ErrTooLong whatever the size of int.
+func (l *Lexer) checkAlloc64(n uint64) bool {
+	if l.maxAlloc > 0 && n > uint64(l.maxAlloc) {
+		l.setError(ErrTooLong)
+		return false
+	}
+	return true
+}
+
+// checkAllocN is like checkAlloc, for count elements of size bytes each. It
+// divides rather than multiplies, so that a huge count cannot wrap around to
+// a small allocation.
+func (l *Lexer) checkAllocN(count, size int) bool {
+	if l.maxAlloc > 0 && size > 0 && uint64(count) > uint64(l.maxAlloc)/uint64(size) {
+		l.setError(ErrTooLong)
+		return false
+	}
+	return true
+}
+
+// Field sets the name of the field that subsequent reads and writes belong
+// to, which is included in any error they cause. It returns l, so that the
+// name can be set inline:
+//
+//	s.Xid = l.Field("xid").Read32()
+func (l *Lexer) Field(name string) *Lexer {
+	l.field = name
+	return l
+}
+
+// setError records err, along with the current field and offset, in a
+// *FieldError. The first error recorded is the one returned by Error. A nil
+// err is ignored.
+func (l *Lexer) setError(err error) {
+	if err == nil {
+		return
+	}
+	ferr := &FieldError{
+		Field:  l.fieldPath(),
+		Offset: l.Tell(),
+		Err:    err,
+	}
+	if l.err == nil {
+		l.err = ferr
+	}
+	l.errs = append(l.errs, ferr)
+}
+
+// consume returns a slice of the next n bytes from the buffer.
+//
+// If there aren't enough bytes left, consume returns nil and sets the error.
+func (l *Lexer) consume(n int) []byte {
+	v, err := l.Buffer.ReadN(n)
+	if err != nil {
+		l.setError(err)
+		return nil
+	}
+	if l.tap != nil {
+		l.tap.Write(v)
+	}
+	return v
+}
+
+// peek returns a slice of the next n bytes from the buffer without consuming
+// them.
+//
+// If n is negative or there aren't enough bytes left, peek returns nil and
+// sets the error.
+func (l *Lexer) peek(n int) []byte {
+	if n < 0 {
+		l.setError(ErrInvalidSize)
+		return nil
+	}
+	if !l.Has(n) {
+		l.setError(io.ErrUnexpectedEOF)
+		return nil
+	}
+	return l.Data()[:n]
+}
+
+// append returns a newly appended n-size slice of the buffer to write to.
+//
+// If n is negative or would make the buffer too large, append returns nil and
+// sets the error.
+func (l *Lexer) append(n int) []byte {
+	if n < 0 {
+		l.setError(ErrInvalidSize)
+		return nil
+	}
+	if n > maxInt-l.size() || (l.maxSize > 0 && l.size()+n > l.maxSize) {
+		l.setError(ErrTooLong)
+		return nil
+	}
+	if l.sink != nil && len(l.data) > 0 && len(l.data)+n > l.sink.size {
+		l.flush()
+	}
+	if l.tap == nil {
+		return l.Buffer.WriteN(n)
+	}
+	l.flushTap()
+	b := l.Buffer.WriteN(n)
+	if !l.counting {
+		// A ring Buffer may have dropped bytes that were fed already.
+		l.tapped = len(l.data) - len(b)
+	}
+	return b
+}
+
+// Error returns an error if an error occurred reading from the buffer.
+//
+// The error is a *FieldError wrapping the first error that occurred.
+func (l *Lexer) Error() error {
+	return l.err
+}
+
+// Errors returns every error that occurred reading from or writing to the
+// buffer, in order. Each is a *FieldError.
+func (l *Lexer) Errors() []error {
+	errs := make([]error, len(l.errs))
+	copy(errs, l.errs)
+	return errs
+}
+
+// Finished returns true if all data in the buffer has been consumed.
+func (l *Lexer) Finished() bool {
+	return !l.Has(1)
+}
+
+// AssertFinished sets the error to ErrUnreadBytes if there is data left to
+// read in the buffer.
+//
+// Calling it at the end of Unmarshal catches parsers that read too little of
+// a fixed-size record.
+func (l *Lexer) AssertFinished() {
+	if !l.Finished() {
+		l.setError(ErrUnreadBytes)
+	}
+}
+
+// Read8 reads a byte from the Buffer.
+//
+// If an error occurred, Error() will return a non-nil error.
+func (l *Lexer) Read8() uint8 {
+	v := l.consume(1)
+	if v == nil {
+		return 0
+	}
+	return uint8(v[0])
+}
+
+// Read16 reads a 16-bit value from the Buffer.
+//
+// If an error occurred, Error() will return a non-nil error.
+func (l *Lexer) Read16() uint16 {
+	v := l.consume(2)
+	if v == nil {
+		return 0
+	}
+	return l.order.Uint16(v)
+}
+
+// Read24 reads a 24-bit value from the Buffer.
+//
+// If an error occurred, Error() will return a non-nil error.
+func (l *Lexer) Read24() uint32 {
+	v := l.consume(3)
+	if v == nil {
+		return 0
+	}
+	if littleEndian(l.order) {
+		return uint32(v[0]) | uint32(v[1])<<8 | uint32(v[2])<<16
+	}
+	return uint32(v[0])<<16 | uint32(v[1])<<8 | uint32(v[2])
+}
+
+// Read32 reads a 32-bit value from the Buffer.
+//
+// If an error occurred, Error() will return a non-nil error.
+func (l *Lexer) Read32() uint32 {
+	v := l.consume(4)
+	if v == nil {
+		return 0
+	}
+	return l.order.Uint32(v)
+}
+
+// Read64 reads a 64-bit value from the Buffer.
+//
+// If an error occurred, Error() will return a non-nil error.
+func (l *Lexer) Read64() uint64 {
+	v := l.consume(8)
+	if v == nil {
+		return 0
+	}
+	return l.order.Uint64(v)
+}
+
+// ReadBool reads a one-byte boolean from the Buffer. Zero is false, and any
+// other value is true, except in strict mode, where values other than 0 and
+// 1 set ErrNonCanonical.
+//
+// If an error occurred, Error() will return a non-nil error.
+func (l *Lexer) ReadBool() bool {
+	v := l.Read8()
+	if l.strict && v > 1 {
+		l.setError(ErrNonCanonical)
+		return false
+	}
+	return v != 0
+}
+
+// ReadUintN reads an n-byte unsigned value from the Buffer, for n in 1..8.
+//
+// If an error occurred, Error() will return a non-nil error.
+func (l *Lexer) ReadUintN(n int) uint64 {
+	if n < 1 || n > 8 {
+		l.setError(ErrInvalidSize)
+		return 0
+	}
+	v := l.consume(n)
+	if v == nil {
+		return 0
+	}
+	var u uint64
+	if littleEndian(l.order) {
+		for i := n - 1; i >= 0; i-- {
+			u = u<<8 | uint64(v[i])
+		}
+	} else {
+		for i := 0; i < n; i++ {
+			u = u<<8 | uint64(v[i])
+		}
+	}
+	return u
+}
+
+// Read8s reads a signed byte from the Buffer.
+//
+// If an error occurred, Error() will return a non-nil error.
+func (l *Lexer) Read8s() int8 {
+	return int8(l.Read8())
+}
+
+// Read16s reads a signed 16-bit value from the Buffer.
+//
+// If an error occurred, Error() will return a non-nil error.
+func (l *Lexer) Read16s() int16 {
+	return int16(l.Read16())
+}
+
+// Read32s reads a signed 32-bit value from the Buffer.
+//
+// If an error occurred, Error() will return a non-nil error.
+func (l *Lexer) Read32s() int32 {
+	return int32(l.Read32())
+}
+
+// Read64s reads a signed 64-bit value from the Buffer.
+//
+// If an error occurred, Error() will return a non-nil error.
+func (l *Lexer) Read64s() int64 {
+	return int64(l.Read64())
+}
+
+// consumeN returns a slice of the next count elements of size bytes each.
+//
+// If there aren't enough bytes left, consumeN returns nil and sets the error.
+func (l *Lexer) consumeN(count, size int) []byte {
+	if count < 0 {
+		l.setError(ErrInvalidSize)
+		return nil
+	}
+	if !l.checkAllocN(count, size) {
+		return nil
+	}
+	if count > maxInt/size {
+		l.setError(io.ErrUnexpectedEOF)
+		return nil
+	}
+	if !l.Has(count * size) {
+		l.setError(io.ErrUnexpectedEOF)
+		return nil
+	}
+	return l.consume(count * size)
+}
+
+// Read16N reads count 16-bit values from the Buffer.
+//
+// If an error occurred, Error() will return a non-nil error.
+func (l *Lexer) Read16N(count int) []uint16 {
+	v := l.consumeN(count, 2)
+	if v == nil {
+		return nil
+	}
+	s := make([]uint16, count)
+	for i := range s {
+		s[i] = l.order.Uint16(v[2*i:])
+	}
+	return s
+}
+
+// Read32N reads count 32-bit values from the Buffer.
+//
+// If an error occurred, Error() will return a non-nil error.
+func (l *Lexer) Read32N(count int) []uint32 {
+	v := l.consumeN(count, 4)
+	if v == nil {
+		return nil
+	}
+	s := make([]uint32, count)
+	for i := range s {
+		s[i] = l.order.Uint32(v[4*i:])
+	}
+	return s
+}
+
+// Read64N reads count 64-bit values from the Buffer.
+//
+// If an error occurred, Error() will return a non-nil error.
+func (l *Lexer) Read64N(count int) []uint64 {
+	v := l.consumeN(count, 8)
+	if v == nil {
+		return nil
+	}
+	s := make([]uint64, count)
+	for i := range s {
+		s[i] = l.order.Uint64(v[8*i:])
+	}
+	return s
+}
+
+// Read16Until reads 16-bit values up to and including the first one equal to
+// sentinel, and returns the values before it.
+//
+// If the data ends before sentinel, the complete values read are returned
+// and Error() will return io.ErrUnexpectedEOF.
+func (l *Lexer) Read16Until(sentinel uint16) []uint16 {
+	s := []uint16{}
+	for {
+		v := l.consume(2)
+		if v == nil {
+			return s
+		}
+		x := l.order.Uint16(v)
+		if x == sentinel {
+			return s
+		}
+		s = append(s, x)
+	}
+}
+
+// Read32Until reads 32-bit values up to and including the first one equal to
+// sentinel, e.g. a table of offsets ending in 0xffffffff, and returns the
+// values before it.
+//
+// If the data ends before sentinel, the complete values read are returned
+// and Error() will return io.ErrUnexpectedEOF.
+func (l *Lexer) Read32Until(sentinel uint32) []uint32 {
+	s := []uint32{}
+	for {
+		v := l.consume(4)
+		if v == nil {
+			return s
+		}
+		x := l.order.Uint32(v)
+		if x == sentinel {
+			return s
+		}
+		s = append(s, x)
+	}
+}
+
+// Read64Until reads 64-bit values up to and including the first one equal to
+// sentinel, and returns the values before it.
+//
+// If the data ends before sentinel, the complete values read are returned
+// and Error() will return io.ErrUnexpectedEOF.
+func (l *Lexer) Read64Until(sentinel uint64) []uint64 {
+	s := []uint64{}
+	for {
+		v := l.consume(8)
+		if v == nil {
+			return s
+		}
+		x := l.order.Uint64(v)
+		if x == sentinel {
+			return s
+		}
+		s = append(s, x)
+	}
+}
+
+// Peek8 returns the next byte in the Buffer without consuming it.
+//
+// If an error occurred, Error() will return a non-nil error.
+func (l *Lexer) Peek8() uint8 {
+	v := l.peek(1)
+	if v == nil {
+		return 0
+	}
+	return v[0]
+}
+
+// Peek16 returns the next 16-bit value in the Buffer without consuming it.
+//
+// If an error occurred, Error() will return a non-nil error.
+func (l *Lexer) Peek16() uint16 {
+	v := l.peek(2)
+	if v == nil {
+		return 0
+	}
+	return l.order.Uint16(v)
+}
+
+// Peek32 returns the next 32-bit value in the Buffer without consuming it.
+//
+// If an error occurred, Error() will return a non-nil error.
+func (l *Lexer) Peek32() uint32 {
+	v := l.peek(4)
+	if v == nil {
+		return 0
+	}
+	return l.order.Uint32(v)
+}
+
+// PeekN returns a copy of the next n bytes in the Buffer without consuming
+// them.
+//
+// If an error occurred, Error() will return a non-nil error.
+func (l *Lexer) PeekN(n int) []byte {
+	v := l.peek(n)
+	if v == nil {
+		return nil
+	}
+	p := make([]byte, n)
+	copy(p, v)
+	return p
+}
+
+// ReadFloat32 reads an IEEE-754 single-precision value from the Buffer.
+//
+// If an error occurred, Error() will return a non-nil error.
+func (l *Lexer) ReadFloat32() float32 {
+	return math.Float32frombits(l.Read32())
+}
+
+// ReadFloat64 reads an IEEE-754 double-precision value from the Buffer.
+//
+// If an error occurred, Error() will return a non-nil error.
+func (l *Lexer) ReadFloat64() float64 {
+	return math.Float64frombits(l.Read64())
+}
+
+// CopyN returns a copy of the next n bytes.
+//
+// If an error occurred, Error() will return a non-nil error.
+func (l *Lexer) CopyN(n int) []byte {
+	if !l.checkAlloc(n) {
+		return nil
+	}
+	v := l.consume(n)
+	if v == nil {
+		return nil
+	}
+
+	p := make([]byte, n)
+	m := copy(p, v)
+	return p[:m]
+}
+
+// ReadAll consumes and returns a copy of all remaining bytes in the Buffer.
+//
+// If an error occurred, Error() will return a non-nil error.
+func (l *Lexer) ReadAll() []byte {
+	if l.maxAlloc > 0 {
+		l.fill(l.maxAlloc + 1)
+	} else {
+		l.fill(maxInt)
+	}
+	return l.CopyN(l.Len())
+}
+
+// ReadAllInto consumes up to len(dst) remaining bytes, copies them into dst
+// and returns how many were copied. Unlike ReadAll, it does not allocate, so
+// dst can be reused across calls.
+//
+// If more than len(dst) bytes are left, the rest are left unread; use
+// Finished to tell whether everything fit. To require exactly len(dst)
+// bytes, use ReadBytes instead.
+func (l *Lexer) ReadAllInto(dst []byte) int {
+	l.fill(len(dst))
+	n := len(dst)
+	if l.Len() < n {
+		n = l.Len()
+	}
+	return copy(dst, l.consume(n))
+}
+
+// ReadBytes reads exactly len(p) values from the Buffer.
+//
+// If an error occurred, Error() will return a non-nil error.
+func (l *Lexer) ReadBytes(p []byte) {
+	copy(p, l.consume(len(p)))
+}
+
+// ReadString reads n bytes and returns them as a string.
+//
+// If an error occurred, Error() will return a non-nil error.
+func (l *Lexer) ReadString(n int) string {
+	return string(l.consume(n))
+}
+
+// Read implements io.Reader.Read.
+func (l *Lexer) Read(p []byte) (int, error) {
+	v := l.consume(len(p))
+	if v == nil {
+		return 0, l.Error()
+	}
+	return copy(p, v), nil
+}
+
+// Skip consumes and discards the next n bytes.
+//
+// If an error occurred, Error() will return a non-nil error.
+func (l *Lexer) Skip(n int) {
+	if n < 0 {
+		l.setError(ErrInvalidSize)
+		return
+	}
+	l.consume(n)
+}
+
+// SkipTo consumes and discards bytes up to offset, counted from the start of
+// the Buffer.
+//
+// If offset lies before the current position, Error() will return
+// ErrInvalidOffset.
+func (l *Lexer) SkipTo(offset int) {
+	if offset < l.Tell() {
+		l.setError(ErrInvalidOffset)
+		return
+	}
+	l.consume(offset - l.Tell())
+}
+
+// Limit consumes the next n bytes and returns a Lexer with the same byte
+// order that reads only those bytes.
+//
+// The returned Lexer shares the consumed bytes with l but cannot read past
+// them: doing so sets its error to io.ErrUnexpectedEOF. Its positions are
+// relative to the start of the sub-region.
+//
+// If fewer than n bytes are left, both l and the returned Lexer's Error()
+// will return a non-nil error.
+func (l *Lexer) Limit(n int) *Lexer {
+	errs := len(l.errs)
+	var v []byte
+	if n < 0 {
+		l.setError(ErrInvalidSize)
+	} else {
+		v = l.consume(n)
+	}
+	sub := l.view(v)
+	if err := l.errorSince(errs); err != nil {
+		sub.err, sub.errs = err, []error{err}
+	}
+	return sub
+}
+
+// view returns a Lexer with the same byte order, limits and mode as l that
+// reads p.
+func (l *Lexer) view(p []byte) *Lexer {
+	// Cap the slice so writes to the view can't clobber the bytes after it.
+	sub := NewLexer(NewBuffer(p[:len(p):len(p)]), l.order)
+	sub.maxAlloc = l.maxAlloc
+	sub.maxSize = l.maxSize
+	sub.strict = l.strict
+	return sub
+}
+
+// Consumed returns a Lexer with the same byte order that reads the bytes l
+// has already consumed, without copying them, e.g. to checksum a region that
+// was just parsed.
+//
+// The returned Lexer shares its bytes with l: changing them in place, for
+// example through Data() or PlaceLength16, changes them for both. Appending
+// to either never affects the other.
+func (l *Lexer) Consumed() *Lexer {
+	return l.view(l.data[:l.off])
+}
+
+// Remaining returns a Lexer with the same byte order that reads the bytes l
+// has not consumed yet, without copying them or moving l's read position.
+//
+// The returned Lexer shares its bytes with l like Consumed. For a Lexer
+// backed by an io.Reader, it only holds the bytes already read from it.
+func (l *Lexer) Remaining() *Lexer {
+	return l.view(l.Data())
+}
+
+// Split returns both Consumed() and Remaining().
+func (l *Lexer) Split() (consumed, remaining *Lexer) {
+	return l.Consumed(), l.Remaining()
+}
+
+// AlignRead consumes and discards bytes until the read position is a
+// multiple of n, counted from the start of the Buffer.
+//
+// If an error occurred, Error() will return a non-nil error.
+func (l *Lexer) AlignRead(n int) {
+	if n < 1 {
+		l.setError(ErrInvalidSize)
+		return
+	}
+	if r := l.Tell() % n; r != 0 {
+		l.consume(n - r)
+	}
+}
+
+// Mark returns the current read position, to be passed to Rewind.
+func (l *Lexer) Mark() int {
+	return l.Tell()
+}
+
+// Rewind moves the read position back to mark, as returned by an earlier
+// call to Mark, so that the bytes after it will be read again.
+//
+// If mark is not a valid position in the Buffer, Error() will return
+// ErrInvalidOffset.
+func (l *Lexer) Rewind(mark int) {
+	if mark < 0 || mark > len(l.data) {
+		l.setError(ErrInvalidOffset)
+		return
+	}
+	l.off = mark
+}
+
+// WriteMark returns the current write position, to be passed to
+// RegionSince, PlaceLength16 and the other methods that take a write
+// position. Unlike Mark, it counts all bytes in the Buffer, consumed or not;
+// both count from the start of the Buffer, so a Mark is also a valid write
+// position.
+func (l *Lexer) WriteMark() int {
+	return l.writeEnd()
+}
+
+// writeBase returns the write position of the first byte in the Buffer,
+// which is 0 unless MarshalTo flushed earlier chunks.
+func (l *Lexer) writeBase() int {
+	if l.sink == nil {
+		return 0
+	}
+	return l.sink.flushed
+}
+
+// writeEnd returns the current write position.
+func (l *Lexer) writeEnd() int {
+	return l.writeBase() + l.size()
+}
+
+// writeIndex returns the index in the Buffer of write position pos. If pos
+// was flushed by MarshalTo or is past the end, it sets ErrInvalidOffset and
+// returns false.
+func (l *Lexer) writeIndex(pos int) (int, bool) {
+	i := pos - l.writeBase()
+	if pos < 0 || i < 0 || i > l.size() {
+		l.setError(ErrInvalidOffset)
+		return 0, false
+	}
+	return i, true
+}
+
+// RegionSince returns the bytes written since mark, as returned by an
+// earlier call to WriteMark, e.g. to hash them for a trailer:
+//
+//	mark := l.WriteMark()
+//	m.Marshal(l)
+//	h := sha256.Sum256(l.RegionSince(mark))
+//	l.WriteBytes(h[:])
+//
+// The region is looked up by offset when RegionSince is called, so it is
+// correct even if the Buffer was reallocated since WriteMark. The returned
+// slice itself shares the Buffer's bytes and is only valid until the next
+// write.
+//
+// If mark is not a valid write position, RegionSince returns nil and
+// Error() will return ErrInvalidOffset.
+func (l *Lexer) RegionSince(mark int) []byte {
+	i, ok := l.writeIndex(mark)
+	if !ok || l.counting {
+		return nil
+	}
+	return l.data[i:len(l.data):len(l.data)]
+}
+
+// Checkpoint returns a token for the current end of the Buffer, to be passed
+// to Rollback. It is the same position as returned by WriteMark.
+func (l *Lexer) Checkpoint() int {
+	return l.writeEnd()
+}
+
+// Rollback discards all bytes appended since checkpoint was returned by
+// Checkpoint, e.g. to undo a partially marshaled record:
+//
+//	cp := l.Checkpoint()
+//	r.Marshal(l)
+//	if !r.Valid() {
+//		l.Rollback(cp)
+//	}
+//
+// If the read position was past checkpoint, it is moved back to it. Errors
+// set since the checkpoint are kept.
+//
+// If checkpoint is past the end of the Buffer, Error() will return
+// ErrInvalidOffset.
+func (l *Lexer) Rollback(checkpoint int) {
+	i, ok := l.writeIndex(checkpoint)
+	if !ok {
+		return
+	}
+	if l.counting {
+		l.n = i
+		return
+	}
+	l.data = l.data[:i]
+	if l.off > i {
+		l.off = i
+	}
+}
+
+// Truncate discards all but the first n unconsumed bytes of the Buffer, like
+// Buffer.Truncate.
+//
+// If n is negative or greater than Len(), nothing is discarded and Error()
+// will return ErrInvalidSize.
+func (l *Lexer) Truncate(n int) {
+	l.setError(l.Buffer.Truncate(n))
+}
+
+// TruncateTo discards all but the first length bytes written to the Buffer,
+// counting consumed bytes like WriteMark does, e.g. to drop a trailing
+// checksum before recomputing it:
+//
+//	l.TruncateTo(l.WriteMark() - 4)
+//
+// It is Rollback in terms of a length: if the read position was past length,
+// it is moved back to it.
+//
+// If length is negative or greater than the number of bytes written, nothing
+// is discarded and Error() will return ErrInvalidOffset.
+func (l *Lexer) TruncateTo(length int) {
+	l.Rollback(length)
+}
+
+// HasStruct returns true if enough bytes are available to ReadData v, a
+// fixed-size value, a slice of them or a pointer to one, e.g. to wait for
+// more data in a streaming reader before decoding a record. Nothing is
+// consumed.
+//
+// If v is not fixed-size, HasStruct returns false and Error() will return
+// ErrUnsupportedType.
+func (l *Lexer) HasStruct(v interface{}) bool {
+	n := binary.Size(v)
+	if n < 0 {
+		l.setError(fmt.Errorf("%w: %T has no fixed size", ErrUnsupportedType, v))
+		return false
+	}
+	return l.Has(n)
+}
+
+// ReadData reads the binary representation of data from the buffer.
+//
+// See binary.Read.
+//
+// If data is not a fixed-size value, a slice of them or a pointer to one,
+// nothing is read and Error() will return ErrUnsupportedType. If fewer than
+// binary.Size(data) bytes are left, nothing is read and Error() will return
+// io.ErrUnexpectedEOF.
+func (l *Lexer) ReadData(data interface{}) {
+	n := binary.Size(data)
+	if n < 0 {
+		l.setError(fmt.Errorf("%w: %T has no fixed size", ErrUnsupportedType, data))
+		return
+	}
+	if !l.Has(n) {
+		l.setError(fmt.Errorf("%w: %T needs %d bytes, have %d", io.ErrUnexpectedEOF, data, n, l.Len()))
+		return
+	}
+	l.setError(binary.Read(l, l.order, data))
+}
+
+// WriteData writes a binary representation of data to the buffer.
+//
+// See binary.Write.
+//
+// If data is not a fixed-size value, a slice of them or a pointer to one,
+// e.g. an int rather than an int32, nothing is written and Error() will
+// return ErrUnsupportedType, naming the type.
+func (l *Lexer) WriteData(data interface{}) {
+	if binary.Size(data) < 0 {
+		l.setError(fmt.Errorf("%w: %T has no fixed size", ErrUnsupportedType, data))
+		return
+	}
+	l.setError(binary.Write(l, l.order, data))
+}
+
+// Write8 writes a byte to the Buffer.
+//
+// If an error occurred, Error() will return a non-nil error.
+func (l *Lexer) Write8(v uint8) {
+	if b := l.append(1); b != nil {
+		b[0] = v
+	}
+}
+
+// Write16 writes a 16-bit value to the Buffer.
+//
+// If an error occurred, Error() will return a non-nil error.
+func (l *Lexer) Write16(v uint16) {
+	if b := l.append(2); b != nil {
+		l.order.PutUint16(b, v)
+	}
+}
+
+// Write24 writes a 24-bit value to the Buffer.
+//
+// If v does not fit in 24 bits, nothing is written and Error() will return
+// ErrOverflow.
+func (l *Lexer) Write24(v uint32) {
+	if v > 0xffffff {
+		l.setError(ErrOverflow)
+		return
+	}
+	b := l.append(3)
+	if b == nil {
+		return
+	}
+	if littleEndian(l.order) {
+		b[0], b[1], b[2] = byte(v), byte(v>>8), byte(v>>16)
+	} else {
+		b[0], b[1], b[2] = byte(v>>16), byte(v>>8), byte(v)
+	}
+}
+
+// Write32 writes a 32-bit value to the Buffer.
+//
+// If an error occurred, Error() will return a non-nil error.
+func (l *Lexer) Write32(v uint32) {
+	if b := l.append(4); b != nil {
+		l.order.PutUint32(b, v)
+	}
+}
+
+// Write64 writes a 64-bit value to the Buffer.
+//
+// If an error occurred, Error() will return a non-nil error.
+func (l *Lexer) Write64(v uint64) {
+	if b := l.append(8); b != nil {
+		l.order.PutUint64(b, v)
+	}
+}
+
+// WriteBool writes b to the Buffer as a one-byte 0x00 or 0x01.
+//
+// If an error occurred, Error() will return a non-nil error.
+func (l *Lexer) WriteBool(b bool) {
+	if b {
+		l.Write8(1)
+	} else {
+		l.Write8(0)
+	}
+}
+
+// WriteUintN writes v as an n-byte unsigned value to the Buffer, for n in
+// 1..8.
+//
+// If v does not fit in n bytes, nothing is written and Error() will return
+// ErrOverflow.
+func (l *Lexer) WriteUintN(n int, v uint64) {
+	if n < 1 || n > 8 {
+		l.setError(ErrInvalidSize)
+		return
+	}
+	if n < 8 && v>>(8*uint(n)) != 0 {
+		l.setError(ErrOverflow)
+		return
+	}
+	b := l.append(n)
+	if b == nil {
+		return
+	}
+	for i := 0; i < n; i++ {
+		if littleEndian(l.order) {
+			b[i] = byte(v >> (8 * uint(i)))
+		} else {
+			b[n-1-i] = byte(v >> (8 * uint(i)))
+		}
+	}
+}
+
+// Write8s writes a signed byte to the Buffer.
+//
+// If an error occurred, Error() will return a non-nil error.
+func (l *Lexer) Write8s(v int8) {
+	l.Write8(uint8(v))
+}
+
+// Write16s writes a signed 16-bit value to the Buffer.
+//
+// If an error occurred, Error() will return a non-nil error.
+func (l *Lexer) Write16s(v int16) {
+	l.Write16(uint16(v))
+}
+
+// Write32s writes a signed 32-bit value to the Buffer.
+//
+// If an error occurred, Error() will return a non-nil error.
+func (l *Lexer) Write32s(v int32) {
+	l.Write32(uint32(v))
+}
+
+// Write64s writes a signed 64-bit value to the Buffer.
+//
+// If an error occurred, Error() will return a non-nil error.
+func (l *Lexer) Write64s(v int64) {
+	l.Write64(uint64(v))
+}
+
+// Write16N writes each 16-bit value in s to the Buffer.
+//
+// If an error occurred, Error() will return a non-nil error.
+func (l *Lexer) Write16N(s []uint16) {
+	b := l.append(2 * len(s))
+	if b == nil {
+		return
+	}
+	for i, v := range s {
+		l.order.PutUint16(b[2*i:], v)
+	}
+}
+
+// Write32N writes each 32-bit value in s to the Buffer.
+//
+// If an error occurred, Error() will return a non-nil error.
+func (l *Lexer) Write32N(s []uint32) {
+	b := l.append(4 * len(s))
+	if b == nil {
+		return
+	}
+	for i, v := range s {
+		l.order.PutUint32(b[4*i:], v)
+	}
+}
+
+// Write64N writes each 64-bit value in s to the Buffer.
+//
+// If an error occurred, Error() will return a non-nil error.
+func (l *Lexer) Write64N(s []uint64) {
+	b := l.append(8 * len(s))
+	if b == nil {
+		return
+	}
+	for i, v := range s {
+		l.order.PutUint64(b[8*i:], v)
+	}
+}
+
+// WriteFloat32 writes an IEEE-754 single-precision value to the Buffer.
+//
+// If an error occurred, Error() will return a non-nil error.
+func (l *Lexer) WriteFloat32(v float32) {
+	l.Write32(math.Float32bits(v))
+}
+
+// WriteFloat64 writes an IEEE-754 double-precision value to the Buffer.
+//
+// If an error occurred, Error() will return a non-nil error.
+func (l *Lexer) WriteFloat64(v float64) {
+	l.Write64(math.Float64bits(v))
+}
+
+// Append returns a newly appended n-size Buffer to write to.
+//
+// If an error occurred, Append returns nil and Error() will return a non-nil
+// error.
+func (l *Lexer) Append(n int) []byte {
+	return l.append(n)
+}
+
+// AlignWrite appends pad bytes until the total length of the Buffer,
+// including consumed bytes, is a multiple of n.
+//
+// If an error occurred, Error() will return a non-nil error.
+func (l *Lexer) AlignWrite(n int, pad byte) {
+	if n < 1 {
+		l.setError(ErrInvalidSize)
+		return
+	}
+	if r := l.writeEnd() % n; r != 0 {
+		l.WriteRepeat(pad, n-r)
+	}
+}
+
+// Reserve appends n zero bytes to the Buffer to be filled in later, and
+// returns a slice pointing to them.
+//
+// The returned slice is only valid until the next write to the Buffer: if
+// the write has to grow the underlying array, later changes to the slice are
+// lost. To fill in a length field after further writes, use PlaceLength8,
+// PlaceLength16 or PlaceLength32 instead, which work on write positions.
+//
+// If an error occurred, Reserve returns nil and Error() will return a
+// non-nil error.
+func (l *Lexer) Reserve(n int) []byte {
+	return l.append(n)
+}
+
+// WriteZeroes appends n zero bytes to the Buffer, without allocating them
+// separately first.
+//
+// If n is negative, nothing is written and Error() will return
+// ErrInvalidSize.
+func (l *Lexer) WriteZeroes(n int) {
+	if n < 0 {
+		l.setError(ErrInvalidSize)
+		return
+	}
+	l.append(n)
+}
+
+// WriteRepeat appends n copies of c to the Buffer, e.g. 0xff to fill a
+// region the way erased flash reads.
+//
+// If n is negative, nothing is written and Error() will return
+// ErrInvalidSize.
+func (l *Lexer) WriteRepeat(c byte, n int) {
+	if n < 0 {
+		l.setError(ErrInvalidSize)
+		return
+	}
+	b := l.append(n)
+	for i := range b {
+		b[i] = c
+	}
+}
+
+// placeLength patches the width-byte field at write position start with the
+// number of bytes that follow the field.
+func (l *Lexer) placeLength(start int, width int) {
+	base := l.writeBase()
+	if start >= 0 && start < base {
+		// The field was flushed by MarshalTo.
+		l.setError(ErrInvalidOffset)
+		return
+	}
+	start -= base
+	if start < 0 || start > l.size()-width {
+		l.setError(io.ErrUnexpectedEOF)
+		return
+	}
+	n := uint64(l.size() - start - width)
+	if n>>(8*uint(width)) != 0 {
+		l.setError(ErrOverflow)
+		return
+	}
+	if l.counting {
+		return
+	}
+	f := l.data[start : start+width]
+	for i := 0; i < width; i++ {
+		if littleEndian(l.order) {
+			f[i] = byte(n >> (8 * uint(i)))
+		} else {
+			f[width-1-i] = byte(n >> (8 * uint(i)))
+		}
+	}
+}
+
+// PlaceLength8 sets the 8-bit field at start to the number of bytes written
+// after it. Like every write position, start counts from the start of the
+// Buffer, including consumed bytes, as returned by WriteMark.
+//
+// Use:
+//
+//	start := l.WriteMark()
+//	l.Reserve(1)
+//	l.WriteBytes(body)
+//	l.PlaceLength8(start)
+//
+// If the length does not fit in the field, Error() will return ErrOverflow.
+func (l *Lexer) PlaceLength8(start int) {
+	l.placeLength(start, 1)
+}
+
+// PlaceLength16 sets the 16-bit field at write position start, as returned
+// by WriteMark, to the number of bytes written after it.
+//
+// See PlaceLength8 for an example.
+//
+// If the length does not fit in the field, Error() will return ErrOverflow.
+func (l *Lexer) PlaceLength16(start int) {
+	l.placeLength(start, 2)
+}
+
+// PlaceLength32 sets the 32-bit field at write position start, as returned
+// by WriteMark, to the number of bytes written after it.
+//
+// See PlaceLength8 for an example.
+//
+// If the length does not fit in the field, Error() will return ErrOverflow.
+func (l *Lexer) PlaceLength32(start int) {
+	l.placeLength(start, 4)
+}
+
+// WriteBytes writes p to the Buffer.
+//
+// If an error occurred, Error() will return a non-nil error.
+func (l *Lexer) WriteBytes(p []byte) {
+	copy(l.append(len(p)), p)
+}
+
+// WriteBuffer writes the unconsumed bytes of b to the Buffer, without
+// consuming them from b, e.g. to assemble a frame from a header and a
+// payload marshaled separately. For a b backed by an io.Reader, all of it is
+// read first.
+//
+// If an error occurred, Error() will return a non-nil error.
+func (l *Lexer) WriteBuffer(b *Buffer) {
+	b.fill(maxInt)
+	l.WriteBytes(b.Data())
+}
+
+// WriteString writes s to the Buffer, without first converting it to a byte
+// slice.
+//
+// If an error occurred, Error() will return a non-nil error.
+func (l *Lexer) WriteString(s string) {
+	copy(l.append(len(s)), s)
+}
+
+// Write implements io.Writer.Write.
+//
+// If an error occurred, Error() will return a non-nil error.
+func (l *Lexer) Write(p []byte) (int, error) {
+	n := len(p)
+	var dropped []byte
+	if l.ring > 0 && n > l.ring {
+		// A ring Buffer would only keep the tail.
+		dropped, p = p[:n-l.ring], p[n-l.ring:]
+	}
+	errs := len(l.errs)
+	b := l.append(len(p))
+	if b == nil {
+		return 0, l.errorSince(errs)
+	}
+	if l.tap != nil && !l.counting {
+		// The tap has been fed everything before b, which it gets later.
+		l.tap.Write(dropped)
+	}
+	copy(b, p)
+	return n, nil
+}
+
+// ReadFrom implements io.ReaderFrom. It appends data read from r until EOF,
+// like Write: a counting Lexer only counts it, and a ring Buffer keeps the
+// last bytes.
+//
+// If the data would grow the Buffer beyond the limit set by SetMaxSize, the
+// bytes that fit are appended, no more is read from r, and ReadFrom returns
+// an error wrapping ErrTooLong that Error() will also return. Errors
+// returned by r are returned but not set.
+func (l *Lexer) ReadFrom(r io.Reader) (int64, error) {
+	if l.maxSize == 0 && !l.counting && l.sink == nil && l.tap == nil {
+		return l.Buffer.ReadFrom(r)
+	}
+	var total int64
+	p := make([]byte, minRead)
+	for {
+		n, err := r.Read(p)
+		if l.maxSize > 0 && n > l.maxSize-l.size() {
+			errs := len(l.errs)
+			if room := l.maxSize - l.size(); room > 0 {
+				l.Write(p[:room])
+				total += int64(room)
+			}
+			l.setError(ErrTooLong)
+			return total, l.errorSince(errs)
+		}
+		if _, werr := l.Write(p[:n]); werr != nil {
+			return total, werr
+		}
+		total += int64(n)
+		if err == io.EOF {
+			return total, nil
+		}
+		if err != nil {
+			return total, err
+		}
+	}
+}
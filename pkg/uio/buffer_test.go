@@ -0,0 +1,1874 @@
+// Copyright 2018 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package uio
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/iotest"
+	"time"
+)
+
+func TestLexerRead(t *testing.T) {
+	l := NewBigEndianBuffer([]byte{0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07})
+	if got, want := l.Read8(), uint8(0x01); got != want {
+		t.Errorf("Read8() = %#x, want %#x", got, want)
+	}
+	if got, want := l.Read16(), uint16(0x0203); got != want {
+		t.Errorf("Read16() = %#x, want %#x", got, want)
+	}
+	if got, want := l.Read32(), uint32(0x04050607); got != want {
+		t.Errorf("Read32() = %#x, want %#x", got, want)
+	}
+	if err := l.Error(); err != nil {
+		t.Errorf("Error() = %v, want nil", err)
+	}
+	if got := l.Read64(); got != 0 {
+		t.Errorf("Read64() on empty buffer = %#x, want 0", got)
+	}
+	if err := l.Error(); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("Error() = %v, want %v", err, io.ErrUnexpectedEOF)
+	}
+}
+
+func TestLexerReadSigned(t *testing.T) {
+	for i, tt := range []struct {
+		in    []byte
+		order binary.ByteOrder
+		read  func(l *Lexer) int64
+		want  int64
+		err   error
+	}{
+		{
+			in:    []byte{0xff},
+			order: binary.BigEndian,
+			read:  func(l *Lexer) int64 { return int64(l.Read8s()) },
+			want:  -1,
+		},
+		{
+			in:    []byte{0x80},
+			order: binary.BigEndian,
+			read:  func(l *Lexer) int64 { return int64(l.Read8s()) },
+			want:  math.MinInt8,
+		},
+		{
+			in:    []byte{0xfe, 0xff},
+			order: binary.LittleEndian,
+			read:  func(l *Lexer) int64 { return int64(l.Read16s()) },
+			want:  -2,
+		},
+		{
+			in:    []byte{0xfe, 0xff},
+			order: binary.BigEndian,
+			read:  func(l *Lexer) int64 { return int64(l.Read16s()) },
+			want:  -257,
+		},
+		{
+			in:    []byte{0x80, 0x00, 0x00, 0x00},
+			order: binary.BigEndian,
+			read:  func(l *Lexer) int64 { return int64(l.Read32s()) },
+			want:  math.MinInt32,
+		},
+		{
+			in:    []byte{0xff, 0xff, 0xff, 0x7f},
+			order: binary.LittleEndian,
+			read:  func(l *Lexer) int64 { return int64(l.Read32s()) },
+			want:  math.MaxInt32,
+		},
+		{
+			in:    []byte{0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x80},
+			order: binary.LittleEndian,
+			read:  func(l *Lexer) int64 { return l.Read64s() },
+			want:  math.MinInt64,
+		},
+		{
+			in:    []byte{0xff, 0xff, 0xff},
+			order: binary.BigEndian,
+			read:  func(l *Lexer) int64 { return int64(l.Read32s()) },
+			want:  0,
+			err:   io.ErrUnexpectedEOF,
+		},
+		{
+			in:    nil,
+			order: binary.BigEndian,
+			read:  func(l *Lexer) int64 { return int64(l.Read8s()) },
+			want:  0,
+			err:   io.ErrUnexpectedEOF,
+		},
+	} {
+		t.Run(fmt.Sprintf("Test #%02d", i), func(t *testing.T) {
+			l := NewLexer(NewBuffer(tt.in), tt.order)
+			if got := tt.read(l); got != tt.want {
+				t.Errorf("read = %d, want %d", got, tt.want)
+			}
+			if err := l.Error(); !errors.Is(err, tt.err) {
+				t.Errorf("Error() = %v, want %v", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestLexerWrite(t *testing.T) {
+	l := NewLittleEndianBuffer(nil)
+	l.Write8(0x01)
+	l.Write16(0x0302)
+	l.Write32(0x07060504)
+	l.WriteBytes([]byte{0x08})
+	want := []byte{0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08}
+	if got := l.Data(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Data() = %#v, want %#v", got, want)
+	}
+}
+
+func TestLexerSignedRoundTrip(t *testing.T) {
+	for _, order := range []binary.ByteOrder{binary.BigEndian, binary.LittleEndian} {
+		l := NewLexer(NewBuffer(nil), order)
+		l.Write8s(-1)
+		l.Write8s(math.MinInt8)
+		l.Write16s(math.MinInt16)
+		l.Write16s(-2)
+		l.Write32s(math.MinInt32)
+		l.Write32s(math.MaxInt32)
+		l.Write64s(math.MinInt64)
+		l.Write64s(-1)
+
+		if got, want := l.Read8s(), int8(-1); got != want {
+			t.Errorf("%v: Read8s() = %d, want %d", order, got, want)
+		}
+		if got, want := l.Read8s(), int8(math.MinInt8); got != want {
+			t.Errorf("%v: Read8s() = %d, want %d", order, got, want)
+		}
+		if got, want := l.Read16s(), int16(math.MinInt16); got != want {
+			t.Errorf("%v: Read16s() = %d, want %d", order, got, want)
+		}
+		if got, want := l.Read16s(), int16(-2); got != want {
+			t.Errorf("%v: Read16s() = %d, want %d", order, got, want)
+		}
+		if got, want := l.Read32s(), int32(math.MinInt32); got != want {
+			t.Errorf("%v: Read32s() = %d, want %d", order, got, want)
+		}
+		if got, want := l.Read32s(), int32(math.MaxInt32); got != want {
+			t.Errorf("%v: Read32s() = %d, want %d", order, got, want)
+		}
+		if got, want := l.Read64s(), int64(math.MinInt64); got != want {
+			t.Errorf("%v: Read64s() = %d, want %d", order, got, want)
+		}
+		if got, want := l.Read64s(), int64(-1); got != want {
+			t.Errorf("%v: Read64s() = %d, want %d", order, got, want)
+		}
+		if err := l.Error(); err != nil {
+			t.Errorf("%v: Error() = %v, want nil", order, err)
+		}
+		if l.Len() != 0 {
+			t.Errorf("%v: Len() = %d, want 0", order, l.Len())
+		}
+	}
+}
+
+func TestLexerFloatRoundTrip(t *testing.T) {
+	f32s := []uint32{
+		0x00000000,
+		0x80000000, // -0
+		0x3f800000, // 1.0
+		0x7f800000, // +Inf
+		0xff800000, // -Inf
+		0x7fc00000, // quiet NaN
+		0x7f800001, // signaling NaN
+		0xffc00123, // NaN with payload
+	}
+	f64s := []uint64{
+		0x0000000000000000,
+		0x8000000000000000,
+		0x3ff0000000000000,
+		0x7ff0000000000000,
+		0xfff0000000000000,
+		0x7ff8000000000000,
+		0x7ff0000000000001,
+		0xfff8000000000123,
+	}
+	for _, order := range []binary.ByteOrder{binary.BigEndian, binary.LittleEndian} {
+		l := NewLexer(NewBuffer(nil), order)
+		for _, bits := range f32s {
+			l.WriteFloat32(math.Float32frombits(bits))
+		}
+		for _, bits := range f64s {
+			l.WriteFloat64(math.Float64frombits(bits))
+		}
+		for _, want := range f32s {
+			if got := math.Float32bits(l.ReadFloat32()); got != want {
+				t.Errorf("%v: ReadFloat32() bits = %#08x, want %#08x", order, got, want)
+			}
+		}
+		for _, want := range f64s {
+			if got := math.Float64bits(l.ReadFloat64()); got != want {
+				t.Errorf("%v: ReadFloat64() bits = %#016x, want %#016x", order, got, want)
+			}
+		}
+		if err := l.Error(); err != nil {
+			t.Errorf("%v: Error() = %v, want nil", order, err)
+		}
+	}
+
+	l := NewBigEndianBuffer([]byte{0x3f, 0x80, 0x00, 0x00, 0x01})
+	if got, want := l.ReadFloat32(), float32(1.0); got != want {
+		t.Errorf("ReadFloat32() = %v, want %v", got, want)
+	}
+	if got := l.ReadFloat64(); got != 0 {
+		t.Errorf("ReadFloat64() on short buffer = %v, want 0", got)
+	}
+	if err := l.Error(); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("Error() = %v, want %v", err, io.ErrUnexpectedEOF)
+	}
+}
+
+func TestLexer24(t *testing.T) {
+	for i, tt := range []struct {
+		order binary.ByteOrder
+		v     uint32
+		want  []byte
+	}{
+		{
+			order: binary.BigEndian,
+			v:     0x123456,
+			want:  []byte{0x12, 0x34, 0x56},
+		},
+		{
+			order: binary.LittleEndian,
+			v:     0x123456,
+			want:  []byte{0x56, 0x34, 0x12},
+		},
+		{
+			order: binary.BigEndian,
+			v:     0xffffff,
+			want:  []byte{0xff, 0xff, 0xff},
+		},
+	} {
+		t.Run(fmt.Sprintf("Test #%02d", i), func(t *testing.T) {
+			l := NewLexer(NewBuffer(nil), tt.order)
+			l.Write24(tt.v)
+			if got := l.Data(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Write24(%#x) = %#v, want %#v", tt.v, got, tt.want)
+			}
+			if got := l.Read24(); got != tt.v {
+				t.Errorf("Read24() = %#x, want %#x", got, tt.v)
+			}
+			if err := l.Error(); err != nil {
+				t.Errorf("Error() = %v, want nil", err)
+			}
+		})
+	}
+
+	l := NewBigEndianBuffer(nil)
+	l.Write24(0x1000000)
+	if l.Len() != 0 {
+		t.Errorf("Write24(0x1000000) wrote %d bytes, want 0", l.Len())
+	}
+	if err := l.Error(); !errors.Is(err, ErrOverflow) {
+		t.Errorf("Error() = %v, want %v", err, ErrOverflow)
+	}
+
+	l = NewBigEndianBuffer([]byte{0x01, 0x02})
+	if got := l.Read24(); got != 0 {
+		t.Errorf("Read24() on short buffer = %#x, want 0", got)
+	}
+	if err := l.Error(); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("Error() = %v, want %v", err, io.ErrUnexpectedEOF)
+	}
+}
+
+func TestLexerUintN(t *testing.T) {
+	for i, tt := range []struct {
+		order binary.ByteOrder
+		n     int
+		v     uint64
+		want  []byte
+		err   error
+	}{
+		{
+			order: binary.BigEndian,
+			n:     1,
+			v:     0xfe,
+			want:  []byte{0xfe},
+		},
+		{
+			order: binary.BigEndian,
+			n:     5,
+			v:     0x0102030405,
+			want:  []byte{0x01, 0x02, 0x03, 0x04, 0x05},
+		},
+		{
+			order: binary.LittleEndian,
+			n:     6,
+			v:     0x010203040506,
+			want:  []byte{0x06, 0x05, 0x04, 0x03, 0x02, 0x01},
+		},
+		{
+			order: binary.LittleEndian,
+			n:     8,
+			v:     math.MaxUint64,
+			want:  []byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff},
+		},
+		{
+			order: binary.BigEndian,
+			n:     7,
+			v:     0x0100000000000000,
+			err:   ErrOverflow,
+		},
+		{
+			order: binary.BigEndian,
+			n:     0,
+			err:   ErrInvalidSize,
+		},
+		{
+			order: binary.BigEndian,
+			n:     9,
+			err:   ErrInvalidSize,
+		},
+	} {
+		t.Run(fmt.Sprintf("Test #%02d", i), func(t *testing.T) {
+			l := NewLexer(NewBuffer(nil), tt.order)
+			l.WriteUintN(tt.n, tt.v)
+			if err := l.Error(); !errors.Is(err, tt.err) {
+				t.Fatalf("WriteUintN(%d, %#x) error = %v, want %v", tt.n, tt.v, err, tt.err)
+			}
+			if tt.err != nil {
+				if l.Len() != 0 {
+					t.Errorf("WriteUintN(%d, %#x) wrote %d bytes, want 0", tt.n, tt.v, l.Len())
+				}
+				return
+			}
+			if got := l.Data(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("WriteUintN(%d, %#x) = %#v, want %#v", tt.n, tt.v, got, tt.want)
+			}
+			if got := l.ReadUintN(tt.n); got != tt.v {
+				t.Errorf("ReadUintN(%d) = %#x, want %#x", tt.n, got, tt.v)
+			}
+		})
+	}
+
+	l := NewBigEndianBuffer([]byte{0x01, 0x02})
+	if got := l.ReadUintN(3); got != 0 {
+		t.Errorf("ReadUintN(3) on short buffer = %#x, want 0", got)
+	}
+	if err := l.Error(); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("Error() = %v, want %v", err, io.ErrUnexpectedEOF)
+	}
+}
+
+func TestPlaceLength(t *testing.T) {
+	// Positions count consumed bytes too.
+	l := NewBigEndianBuffer([]byte{0xff})
+	l.Read8()
+	l.Write8(0x01)
+	outer := l.WriteMark()
+	l.Reserve(2)
+	inner := l.WriteMark()
+	l.Reserve(1)
+	// Force the backing array to grow after reserving.
+	l.WriteBytes(make([]byte, 300))
+	l.PlaceLength8(inner)
+	l.PlaceLength16(outer)
+
+	if err := l.Error(); !errors.Is(err, ErrOverflow) {
+		t.Errorf("Error() = %v, want %v", err, ErrOverflow)
+	}
+	d := l.Data()
+	if got, want := d[1:4], []byte{0x01, 0x2d, 0x00}; !reflect.DeepEqual(got, want) {
+		t.Errorf("length fields = %#v, want %#v", got, want)
+	}
+
+	l = NewLittleEndianBuffer(nil)
+	start := l.WriteMark()
+	l.Reserve(4)
+	l.WriteBytes([]byte{0xaa, 0xbb, 0xcc})
+	l.PlaceLength32(start)
+	if got, want := l.Data(), []byte{0x03, 0x00, 0x00, 0x00, 0xaa, 0xbb, 0xcc}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Data() = %#v, want %#v", got, want)
+	}
+	if err := l.Error(); err != nil {
+		t.Errorf("Error() = %v, want nil", err)
+	}
+
+	l.PlaceLength16(6)
+	if err := l.Error(); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("PlaceLength16 past the end: Error() = %v, want %v", err, io.ErrUnexpectedEOF)
+	}
+}
+
+func TestPeek(t *testing.T) {
+	l := NewLittleEndianBuffer([]byte{0x01, 0x02, 0x03, 0x04})
+	if got, want := l.Peek8(), uint8(0x01); got != want {
+		t.Errorf("Peek8() = %#x, want %#x", got, want)
+	}
+	if got, want := l.Peek16(), uint16(0x0201); got != want {
+		t.Errorf("Peek16() = %#x, want %#x", got, want)
+	}
+	if got, want := l.Peek32(), uint32(0x04030201); got != want {
+		t.Errorf("Peek32() = %#x, want %#x", got, want)
+	}
+	p := l.PeekN(2)
+	if want := []byte{0x01, 0x02}; !reflect.DeepEqual(p, want) {
+		t.Errorf("PeekN(2) = %#v, want %#v", p, want)
+	}
+	p[0] = 0xff
+	if got, want := l.Read8(), uint8(0x01); got != want {
+		t.Errorf("Read8() after modifying PeekN result = %#x, want %#x", got, want)
+	}
+	if l.Len() != 3 {
+		t.Errorf("Len() = %d, want 3", l.Len())
+	}
+	if err := l.Error(); err != nil {
+		t.Errorf("Error() = %v, want nil", err)
+	}
+
+	if got := l.Peek32(); got != 0 {
+		t.Errorf("Peek32() on short buffer = %#x, want 0", got)
+	}
+	if err := l.Error(); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("Error() = %v, want %v", err, io.ErrUnexpectedEOF)
+	}
+	if l.Len() != 3 {
+		t.Errorf("Len() after failed Peek32 = %d, want 3", l.Len())
+	}
+}
+
+func TestSkip(t *testing.T) {
+	l := NewBigEndianBuffer([]byte{0x01, 0x02, 0x03, 0x04, 0x05, 0x06})
+	l.Skip(1)
+	if got, want := l.Read8(), uint8(0x02); got != want {
+		t.Errorf("Read8() after Skip(1) = %#x, want %#x", got, want)
+	}
+	l.SkipTo(4)
+	if got, want := l.Read8(), uint8(0x05); got != want {
+		t.Errorf("Read8() after SkipTo(4) = %#x, want %#x", got, want)
+	}
+	l.SkipTo(5)
+	if err := l.Error(); err != nil {
+		t.Errorf("Error() = %v, want nil", err)
+	}
+
+	l.SkipTo(2)
+	if err := l.Error(); !errors.Is(err, ErrInvalidOffset) {
+		t.Errorf("SkipTo(2) backwards: Error() = %v, want %v", err, ErrInvalidOffset)
+	}
+
+	l = NewBigEndianBuffer([]byte{0x01})
+	l.Skip(2)
+	if err := l.Error(); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("Skip(2) past the end: Error() = %v, want %v", err, io.ErrUnexpectedEOF)
+	}
+
+	l = NewBigEndianBuffer([]byte{0x01})
+	l.Skip(-1)
+	if err := l.Error(); !errors.Is(err, ErrInvalidSize) {
+		t.Errorf("Skip(-1): Error() = %v, want %v", err, ErrInvalidSize)
+	}
+}
+
+func TestTell(t *testing.T) {
+	l := NewBigEndianBuffer([]byte{0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08})
+	if got := l.Tell(); got != 0 {
+		t.Errorf("Tell() = %d, want 0", got)
+	}
+	l.Read16()
+	if got := l.Tell(); got != 2 {
+		t.Errorf("Tell() after Read16 = %d, want 2", got)
+	}
+	l.Skip(1)
+	l.ReadN(2)
+	if got := l.Tell(); got != 5 {
+		t.Errorf("Tell() after Skip and ReadN = %d, want 5", got)
+	}
+	l.Write32(0)
+	if got := l.Tell(); got != 5 {
+		t.Errorf("Tell() after Write32 = %d, want 5", got)
+	}
+	l.ReadAll()
+	if got := l.Tell(); got != 12 {
+		t.Errorf("Tell() after ReadAll = %d, want 12", got)
+	}
+	l.Read8()
+	if got := l.Tell(); got != 12 {
+		t.Errorf("Tell() after failed Read8 = %d, want 12", got)
+	}
+}
+
+func TestMarkRewind(t *testing.T) {
+	l := NewBigEndianBuffer([]byte{0x01, 0x02, 0x03, 0x04, 0x05})
+	l.Read8()
+	m := l.Mark()
+	if got, want := l.Read32(), uint32(0x02030405); got != want {
+		t.Errorf("Read32() = %#x, want %#x", got, want)
+	}
+	if l.Len() != 0 {
+		t.Errorf("Len() = %d, want 0", l.Len())
+	}
+
+	l.Rewind(m)
+	if l.Len() != 4 || !l.Has(4) || l.Has(5) {
+		t.Errorf("Len() after Rewind = %d, want 4", l.Len())
+	}
+	if got, want := l.Data(), []byte{0x02, 0x03, 0x04, 0x05}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Data() after Rewind = %#v, want %#v", got, want)
+	}
+	if got, want := l.Read16(), uint16(0x0203); got != want {
+		t.Errorf("Read16() after Rewind = %#x, want %#x", got, want)
+	}
+	if got := l.Tell(); got != 3 {
+		t.Errorf("Tell() = %d, want 3", got)
+	}
+	if err := l.Error(); err != nil {
+		t.Errorf("Error() = %v, want nil", err)
+	}
+
+	l.Rewind(6)
+	if err := l.Error(); !errors.Is(err, ErrInvalidOffset) {
+		t.Errorf("Rewind(6): Error() = %v, want %v", err, ErrInvalidOffset)
+	}
+}
+
+func TestRegionSince(t *testing.T) {
+	l := NewBigEndianBufferSize(2)
+	l.Write8(0x01)
+	l.Read8()
+	mark := l.WriteMark()
+	// Force a reallocation of the Buffer after the mark.
+	l.WriteBytes([]byte("hello, world"))
+	if got, want := l.RegionSince(mark), []byte("hello, world"); !reflect.DeepEqual(got, want) {
+		t.Errorf("RegionSince() = %q, want %q", got, want)
+	}
+	if got := sha256.Sum256(l.RegionSince(mark)); got != sha256.Sum256([]byte("hello, world")) {
+		t.Errorf("sha256 of RegionSince() = %x, want that of %q", got, "hello, world")
+	}
+	if got := l.RegionSince(l.WriteMark()); len(got) != 0 {
+		t.Errorf("RegionSince(WriteMark()) = %#v, want empty", got)
+	}
+
+	if got := l.RegionSince(100); got != nil {
+		t.Errorf("RegionSince(100) = %#v, want nil", got)
+	}
+	if err := l.Error(); !errors.Is(err, ErrInvalidOffset) {
+		t.Errorf("RegionSince(100): Error() = %v, want %v", err, ErrInvalidOffset)
+	}
+}
+
+func TestRollback(t *testing.T) {
+	l := NewBigEndianBuffer(nil)
+	l.Write16(0x0102)
+	cp := l.Checkpoint()
+	l.Write32(0x03040506)
+	l.WriteString("partial")
+	l.Rollback(cp)
+	l.Write8(0x07)
+	if got, want := l.Data(), []byte{0x01, 0x02, 0x07}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Data() after Rollback = %#v, want %#v", got, want)
+	}
+
+	// Rolling back bytes that were already read moves the read position.
+	cp = l.Checkpoint()
+	l.Write16(0x0809)
+	l.Skip(4)
+	l.Rollback(cp)
+	if l.Tell() != 3 || l.Len() != 0 {
+		t.Errorf("Tell(), Len() after Rollback = %d, %d, want 3, 0", l.Tell(), l.Len())
+	}
+	if err := l.Error(); err != nil {
+		t.Errorf("Error() = %v, want nil", err)
+	}
+
+	l.Rollback(cp + 1)
+	if err := l.Error(); !errors.Is(err, ErrInvalidOffset) {
+		t.Errorf("Rollback past the end: Error() = %v, want %v", err, ErrInvalidOffset)
+	}
+}
+
+func TestTruncateTo(t *testing.T) {
+	l := NewBigEndianBuffer([]byte{0x01})
+	l.Read8()
+	l.Write16(0x0203)
+	l.Write32(0xdeadbeef)
+	l.TruncateTo(l.WriteMark() - 4)
+	if got, want := l.Data(), []byte{0x02, 0x03}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Data() after TruncateTo = %#v, want %#v", got, want)
+	}
+	if got, want := l.WriteMark(), 3; got != want {
+		t.Errorf("WriteMark() after TruncateTo = %d, want %d", got, want)
+	}
+
+	for _, length := range []int{-1, 4} {
+		l := NewBigEndianBuffer([]byte{0x01, 0x02, 0x03})
+		l.TruncateTo(length)
+		if err := l.Error(); !errors.Is(err, ErrInvalidOffset) {
+			t.Errorf("TruncateTo(%d): Error() = %v, want %v", length, err, ErrInvalidOffset)
+		}
+		if got := l.Len(); got != 3 {
+			t.Errorf("Len() after TruncateTo(%d) = %d, want 3", length, got)
+		}
+	}
+}
+
+func TestSetOrder(t *testing.T) {
+	for i, tt := range []struct {
+		in    []byte
+		order binary.ByteOrder
+	}{
+		{
+			in:    []byte{0xfe, 0xff, 0x12, 0x34, 0x00, 0x00, 0x00, 0x01},
+			order: binary.BigEndian,
+		},
+		{
+			in:    []byte{0xff, 0xfe, 0x34, 0x12, 0x01, 0x00, 0x00, 0x00},
+			order: binary.LittleEndian,
+		},
+	} {
+		t.Run(fmt.Sprintf("Test #%02d", i), func(t *testing.T) {
+			l := NewBigEndianBuffer(tt.in)
+			if bom := l.Read16(); bom == 0xfffe {
+				l.SetOrder(binary.LittleEndian)
+			}
+			if got := l.Order(); got != tt.order {
+				t.Errorf("Order() = %v, want %v", got, tt.order)
+			}
+			if got, want := l.Read16(), uint16(0x1234); got != want {
+				t.Errorf("Read16() = %#x, want %#x", got, want)
+			}
+			if got, want := l.Read32(), uint32(1); got != want {
+				t.Errorf("Read32() = %#x, want %#x", got, want)
+			}
+		})
+	}
+}
+
+func TestAlign(t *testing.T) {
+	l := NewBigEndianBuffer(nil)
+	l.WriteBytes([]byte{'a', 'b', 'c'})
+	l.AlignWrite(4, 0)
+	l.Write32(0x01020304)
+	l.AlignWrite(4, 0xff)
+	l.Write8(0x05)
+	l.AlignWrite(8, 0xff)
+	want := []byte{'a', 'b', 'c', 0x00, 0x01, 0x02, 0x03, 0x04, 0x05, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}
+	if got := l.Data(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("Data() = %#v, want %#v", got, want)
+	}
+
+	l.Skip(3)
+	l.AlignRead(4)
+	if got, want := l.Read32(), uint32(0x01020304); got != want {
+		t.Errorf("Read32() after AlignRead(4) = %#x, want %#x", got, want)
+	}
+	l.AlignRead(4)
+	if got, want := l.Tell(), 8; got != want {
+		t.Errorf("Tell() after aligned AlignRead(4) = %d, want %d", got, want)
+	}
+	l.Read8()
+	l.AlignRead(8)
+	if got := l.Len(); got != 0 {
+		t.Errorf("Len() after AlignRead(8) = %d, want 0", got)
+	}
+	if err := l.Error(); err != nil {
+		t.Errorf("Error() = %v, want nil", err)
+	}
+
+	// Alignment is relative to the start of the buffer, not the unread
+	// data.
+	l = NewBigEndianBuffer([]byte{0x01, 0x02})
+	l.Read8()
+	l.AlignWrite(4, 0)
+	if got, want := l.Data(), []byte{0x02, 0x00, 0x00}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Data() = %#v, want %#v", got, want)
+	}
+
+	l.AlignRead(0)
+	if err := l.Error(); !errors.Is(err, ErrInvalidSize) {
+		t.Errorf("AlignRead(0): Error() = %v, want %v", err, ErrInvalidSize)
+	}
+}
+
+func TestWriteRepeat(t *testing.T) {
+	l := NewBigEndianBuffer(nil)
+	l.WriteZeroes(3)
+	l.WriteRepeat(0xff, 2)
+	l.WriteZeroes(0)
+	l.WriteRepeat(0xaa, 1)
+	if got, want := l.Data(), []byte{0x00, 0x00, 0x00, 0xff, 0xff, 0xaa}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Data() = %#v, want %#v", got, want)
+	}
+	if err := l.Error(); err != nil {
+		t.Errorf("Error() = %v, want nil", err)
+	}
+
+	l.WriteZeroes(-1)
+	l.WriteRepeat(0xff, -1)
+	if got := l.Errors(); len(got) != 2 || !errors.Is(got[0], ErrInvalidSize) || !errors.Is(got[1], ErrInvalidSize) {
+		t.Errorf("Errors() = %v, want two %v", got, ErrInvalidSize)
+	}
+	if l.Len() != 6 {
+		t.Errorf("Len() = %d, want 6", l.Len())
+	}
+
+	// Zeroes written over a reused array must still be zero.
+	l.Truncate(0)
+	l.WriteZeroes(6)
+	if got, want := l.Data(), make([]byte, 6); !reflect.DeepEqual(got, want) {
+		t.Errorf("Data() = %#v, want %#v", got, want)
+	}
+	if n := testing.AllocsPerRun(10, func() {
+		l.Truncate(0)
+		l.WriteZeroes(6)
+	}); n != 0 {
+		t.Errorf("WriteZeroes into spare capacity made %v allocations, want 0", n)
+	}
+}
+
+func TestReadWriteN(t *testing.T) {
+	l := NewBigEndianBuffer(nil)
+	l.Write16N([]uint16{0x0102, 0x0304})
+	l.Write32N([]uint32{0x05060708})
+	l.Write64N([]uint64{0x090a0b0c0d0e0f10, 0x1112131415161718})
+	l.Write32N(nil)
+	if got, want := l.Len(), 4+4+16; got != want {
+		t.Fatalf("Len() = %d, want %d", got, want)
+	}
+
+	if got, want := l.Read16N(2), []uint16{0x0102, 0x0304}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Read16N(2) = %#v, want %#v", got, want)
+	}
+	if got, want := l.Read32N(1), []uint32{0x05060708}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Read32N(1) = %#v, want %#v", got, want)
+	}
+	if got, want := l.Read64N(2), []uint64{0x090a0b0c0d0e0f10, 0x1112131415161718}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Read64N(2) = %#v, want %#v", got, want)
+	}
+	if got := l.Read32N(0); len(got) != 0 {
+		t.Errorf("Read32N(0) = %#v, want empty", got)
+	}
+	if err := l.Error(); err != nil {
+		t.Errorf("Error() = %v, want nil", err)
+	}
+
+	l = NewLittleEndianBuffer([]byte{0x01, 0x00, 0x02})
+	if got := l.Read16N(2); got != nil {
+		t.Errorf("Read16N(2) on short buffer = %#v, want nil", got)
+	}
+	if err := l.Error(); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("Error() = %v, want %v", err, io.ErrUnexpectedEOF)
+	}
+	if l.Len() != 3 {
+		t.Errorf("Len() after failed Read16N = %d, want 3", l.Len())
+	}
+
+	l = NewLittleEndianBuffer(nil)
+	l.Read32N(-1)
+	if err := l.Error(); !errors.Is(err, ErrInvalidSize) {
+		t.Errorf("Read32N(-1): Error() = %v, want %v", err, ErrInvalidSize)
+	}
+}
+
+func TestReadUntilSentinel(t *testing.T) {
+	l := NewBigEndianBuffer([]byte{
+		0x00, 0x01, 0x00, 0x02, 0xff, 0xff,
+		0x00, 0x00, 0x00, 0x10, 0xff, 0xff, 0xff, 0xff,
+		0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff,
+		0x00, 0x03, 0x00,
+	})
+	if got, want := l.Read16Until(0xffff), []uint16{1, 2}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Read16Until() = %#v, want %#v", got, want)
+	}
+	if got, want := l.Read32Until(0xffffffff), []uint32{0x10}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Read32Until() = %#v, want %#v", got, want)
+	}
+	if got, want := l.Read64Until(^uint64(0)), []uint64{}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Read64Until() with only the sentinel = %#v, want %#v", got, want)
+	}
+	if err := l.Error(); err != nil {
+		t.Errorf("Error() = %v, want nil", err)
+	}
+
+	if got, want := l.Read16Until(0xffff), []uint16{3}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Read16Until() without a sentinel = %#v, want %#v", got, want)
+	}
+	if err := l.Error(); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("Read16Until() without a sentinel: Error() = %v, want %v", err, io.ErrUnexpectedEOF)
+	}
+	if l.Len() != 1 {
+		t.Errorf("Len() after Read16Until() = %d, want 1", l.Len())
+	}
+}
+
+func TestNegativeSize(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		read func(l *Lexer)
+	}{
+		{"ReadN", func(l *Lexer) {
+			_, err := l.Buffer.ReadN(-1)
+			l.setError(err)
+		}},
+		{"PeekN", func(l *Lexer) { l.PeekN(-1) }},
+		{"CopyN", func(l *Lexer) { l.CopyN(-1) }},
+		{"ReadFixedString", func(l *Lexer) { l.ReadFixedString(-1) }},
+		{"ReadString", func(l *Lexer) { l.ReadString(-1) }},
+		{"ReadHardwareAddr", func(l *Lexer) { l.ReadHardwareAddr(-1) }},
+		{"ReadBinaryUnmarshaler", func(l *Lexer) { ReadBinaryUnmarshaler(l, -1, new(time.Time)) }},
+	} {
+		l := NewBigEndianBuffer([]byte{0x01, 0x02, 0x03})
+		l.Read8()
+		tt.read(l)
+		if err := l.Error(); !errors.Is(err, ErrInvalidSize) {
+			t.Errorf("%s(-1): Error() = %v, want %v", tt.name, err, ErrInvalidSize)
+		}
+		if got := l.Len(); got != 2 {
+			t.Errorf("%s(-1): Len() = %d, want 2", tt.name, got)
+		}
+	}
+}
+
+func TestReadWriteString(t *testing.T) {
+	l := NewBigEndianBufferSize(16)
+	l.WriteString("hello")
+	l.WriteString("")
+	l.WriteString(", world")
+	if got, want := l.ReadString(5), "hello"; got != want {
+		t.Errorf("ReadString(5) = %q, want %q", got, want)
+	}
+	if got, want := l.ReadString(0), ""; got != want {
+		t.Errorf("ReadString(0) = %q, want %q", got, want)
+	}
+	if got, want := l.ReadString(7), ", world"; got != want {
+		t.Errorf("ReadString(7) = %q, want %q", got, want)
+	}
+	if err := l.Error(); err != nil {
+		t.Errorf("Error() = %v, want nil", err)
+	}
+	if got := l.ReadString(1); got != "" {
+		t.Errorf("ReadString(1) of empty Buffer = %q, want empty", got)
+	}
+	if err := l.Error(); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("Error() = %v, want %v", err, io.ErrUnexpectedEOF)
+	}
+
+	l = NewBigEndianBufferSize(16)
+	s := strings.Repeat("x", 16)
+	if n := testing.AllocsPerRun(10, func() {
+		l.Reset()
+		l.WriteString(s)
+	}); n != 0 {
+		t.Errorf("WriteString into spare capacity made %v allocations, want 0", n)
+	}
+}
+
+func TestBool(t *testing.T) {
+	l := NewBigEndianBuffer(nil)
+	l.WriteBool(true)
+	l.WriteBool(false)
+	l.Write8(0x80)
+	if got, want := l.Data(), []byte{0x01, 0x00, 0x80}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Data() = %#v, want %#v", got, want)
+	}
+	for _, want := range []bool{true, false, true} {
+		if got := l.ReadBool(); got != want {
+			t.Errorf("ReadBool() = %v, want %v", got, want)
+		}
+	}
+	if err := l.Error(); err != nil {
+		t.Errorf("Error() = %v, want nil", err)
+	}
+	if l.ReadBool() {
+		t.Errorf("ReadBool() on empty buffer = true, want false")
+	}
+	if err := l.Error(); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("Error() = %v, want %v", err, io.ErrUnexpectedEOF)
+	}
+}
+
+func TestWriteTo(t *testing.T) {
+	l := NewBigEndianBuffer([]byte{0x01, 0x02, 0x03, 0x04})
+	l.Read8()
+	var w bytes.Buffer
+	n, err := l.WriteTo(&w)
+	if err != nil || n != 3 {
+		t.Errorf("WriteTo() = %d, %v, want 3, nil", n, err)
+	}
+	if got, want := w.Bytes(), []byte{0x02, 0x03, 0x04}; !reflect.DeepEqual(got, want) {
+		t.Errorf("WriteTo() wrote %#v, want %#v", got, want)
+	}
+	if l.Len() != 0 || l.Tell() != 4 {
+		t.Errorf("after WriteTo: Len() = %d, Tell() = %d, want 0, 4", l.Len(), l.Tell())
+	}
+}
+
+func TestReadFrom(t *testing.T) {
+	content := make([]byte, 3*minRead+7)
+	for i := range content {
+		content[i] = byte(i)
+	}
+
+	l := NewBigEndianBuffer([]byte{0xff})
+	l.Read8()
+	n, err := l.ReadFrom(bytes.NewReader(content))
+	if err != nil || n != int64(len(content)) {
+		t.Errorf("ReadFrom() = %d, %v, want %d, nil", n, err, len(content))
+	}
+	if l.Tell() != 1 {
+		t.Errorf("Tell() after ReadFrom = %d, want 1", l.Tell())
+	}
+	if got := l.Data(); !reflect.DeepEqual(got, content) {
+		t.Errorf("Data() after ReadFrom does not match the reader's content")
+	}
+
+	r := io.MultiReader(bytes.NewReader([]byte{0x01}), &mockReader{err: io.ErrClosedPipe})
+	l = NewBigEndianBuffer(nil)
+	if n, err := l.ReadFrom(r); n != 1 || !errors.Is(err, io.ErrClosedPipe) {
+		t.Errorf("ReadFrom() = %d, %v, want 1, %v", n, err, io.ErrClosedPipe)
+	}
+
+	// io.Copy respects SetMaxSize.
+	l = NewBigEndianBuffer([]byte{0xff})
+	l.SetMaxSize(16)
+	n, err = io.Copy(l, struct{ io.Reader }{bytes.NewReader(content)})
+	if n != 15 || !errors.Is(err, ErrTooLong) {
+		t.Errorf("io.Copy() with SetMaxSize(16) = %d, %v, want 15, %v", n, err, ErrTooLong)
+	}
+	if got, want := l.Data(), append([]byte{0xff}, content[:15]...); !reflect.DeepEqual(got, want) {
+		t.Errorf("Data() after io.Copy() = %#v, want %#v", got, want)
+	}
+	if err := l.Error(); !errors.Is(err, ErrTooLong) {
+		t.Errorf("Error() after io.Copy() = %v, want %v", err, ErrTooLong)
+	}
+
+	// Write returns its own error, not an earlier one.
+	l = NewBigEndianBuffer(nil)
+	l.SetMaxSize(2)
+	l.Read8()
+	if n, err := l.Write([]byte{1, 2, 3}); n != 0 || !errors.Is(err, ErrTooLong) {
+		t.Errorf("Write() after a failed Read8 = %d, %v, want 0, %v", n, err, ErrTooLong)
+	}
+
+	// A counting Lexer counts what it reads.
+	l = NewCountingLexer(binary.BigEndian)
+	l.Write8(0)
+	if n, err := l.ReadFrom(bytes.NewReader(content)); n != int64(len(content)) || err != nil {
+		t.Errorf("ReadFrom() on a counting Lexer = %d, %v, want %d, nil", n, err, len(content))
+	}
+	if got, want := l.Len(), 1+len(content); got != want {
+		t.Errorf("Len() of a counting Lexer after ReadFrom = %d, want %d", got, want)
+	}
+
+	// A ring Buffer keeps the tail.
+	l = NewLexer(NewRingBuffer(16), binary.BigEndian)
+	l.SetMaxSize(1 << 20)
+	if n, err := l.ReadFrom(bytes.NewReader(content)); n != int64(len(content)) || err != nil {
+		t.Errorf("ReadFrom() on a ring Buffer = %d, %v, want %d, nil", n, err, len(content))
+	}
+	if got, want := l.Data(), content[len(content)-16:]; !reflect.DeepEqual(got, want) {
+		t.Errorf("Data() of a ring Buffer after ReadFrom = %#v, want %#v", got, want)
+	}
+}
+
+func TestLimit(t *testing.T) {
+	l := NewBigEndianBuffer([]byte{0x01, 0x00, 0x02, 0xaa, 0xbb, 0xcc})
+	l.Read8()
+	sub := l.Limit(3)
+	if got, want := l.Tell(), 4; got != want {
+		t.Errorf("parent Tell() after Limit(3) = %d, want %d", got, want)
+	}
+	if got, want := sub.Read16(), uint16(0x0002); got != want {
+		t.Errorf("sub.Read16() = %#x, want %#x", got, want)
+	}
+	if got, want := sub.Read8(), uint8(0xaa); got != want {
+		t.Errorf("sub.Read8() = %#x, want %#x", got, want)
+	}
+	if err := sub.Error(); err != nil {
+		t.Errorf("sub.Error() = %v, want nil", err)
+	}
+	sub.Read8()
+	if err := sub.Error(); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("sub.Read8() past the limit: Error() = %v, want %v", err, io.ErrUnexpectedEOF)
+	}
+
+	// Writes to the sub-Lexer must not overwrite the parent's data.
+	sub.Write8(0xff)
+	if got, want := l.Read16(), uint16(0xbbcc); got != want {
+		t.Errorf("parent Read16() = %#x, want %#x", got, want)
+	}
+	if err := l.Error(); err != nil {
+		t.Errorf("parent Error() = %v, want nil", err)
+	}
+
+	sub = l.Limit(1)
+	if !errors.Is(l.Error(), io.ErrUnexpectedEOF) || !errors.Is(sub.Error(), io.ErrUnexpectedEOF) {
+		t.Errorf("Limit(1) past the end: Error() = %v, sub.Error() = %v, want %v", l.Error(), sub.Error(), io.ErrUnexpectedEOF)
+	}
+
+	// The sub-Lexer gets the error Limit caused, not an earlier one.
+	sub = l.Limit(-1)
+	if err := sub.Error(); !errors.Is(err, ErrInvalidSize) {
+		t.Errorf("Limit(-1) after a failed read: sub.Error() = %v, want %v", err, ErrInvalidSize)
+	}
+	if got := len(sub.Errors()); got != 1 {
+		t.Errorf("Limit(-1): len(sub.Errors()) = %d, want 1", got)
+	}
+}
+
+func TestSplit(t *testing.T) {
+	l := NewBigEndianBuffer([]byte{0x01, 0x02, 0x03, 0x04, 0x05})
+	l.Read16()
+	consumed, remaining := l.Split()
+	if got, want := consumed.Data(), []byte{0x01, 0x02}; !reflect.DeepEqual(got, want) {
+		t.Errorf("consumed Data() = %#v, want %#v", got, want)
+	}
+	if got, want := remaining.Read16(), uint16(0x0304); got != want {
+		t.Errorf("remaining Read16() = %#x, want %#x", got, want)
+	}
+	if l.Tell() != 2 {
+		t.Errorf("Tell() after reading remaining = %d, want 2", l.Tell())
+	}
+
+	// The views share bytes, but appending to one does not clobber the
+	// other.
+	consumed.Data()[0] = 0xff
+	consumed.Write8(0xaa)
+	remaining.Write8(0xbb)
+	l.Write8(0x06)
+	if got, want := l.Consumed().Data(), []byte{0xff, 0x02}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Consumed() = %#v, want %#v", got, want)
+	}
+	if got, want := l.Data(), []byte{0x03, 0x04, 0x05, 0x06}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Data() = %#v, want %#v", got, want)
+	}
+	if got, want := remaining.Data(), []byte{0x05, 0xbb}; !reflect.DeepEqual(got, want) {
+		t.Errorf("remaining Data() = %#v, want %#v", got, want)
+	}
+}
+
+func TestFieldError(t *testing.T) {
+	l := NewBigEndianBuffer([]byte{0x01, 0x02, 0x03, 0x04, 0x05})
+	l.Field("op").Read8()
+	l.Field("xid").Read32()
+	l.Field("secs").Read16()
+	l.Field("flags").Read16()
+
+	err := l.Error()
+	if !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("Error() = %v, want %v", err, io.ErrUnexpectedEOF)
+	}
+	var ferr *FieldError
+	if !errors.As(err, &ferr) {
+		t.Fatalf("Error() = %T, want *FieldError", err)
+	}
+	if ferr.Field != "secs" || ferr.Offset != 5 {
+		t.Errorf("FieldError = {Field: %q, Offset: %d}, want {Field: \"secs\", Offset: 5}", ferr.Field, ferr.Offset)
+	}
+	if got, want := err.Error(), `error in field "secs" at offset 5: unexpected EOF`; got != want {
+		t.Errorf("Error().Error() = %q, want %q", got, want)
+	}
+
+	l = NewBigEndianBuffer([]byte{0x01})
+	l.Read16()
+	if got, want := l.Error().Error(), "error at offset 0: unexpected EOF"; got != want {
+		t.Errorf("Error().Error() = %q, want %q", got, want)
+	}
+
+	// Successful calls pass nil errors along, which must not be recorded.
+	w := NewBigEndianBuffer(nil)
+	w.Field("hdr").WriteData(struct{ A, B uint16 }{1, 2})
+	var v struct{ A, B uint16 }
+	r := NewBigEndianBuffer(w.Data())
+	r.Field("hdr").ReadData(&v)
+	for _, l := range []*Lexer{w, r} {
+		if err := l.Error(); err != nil || len(l.Errors()) != 0 {
+			t.Errorf("Error() after a successful WriteData/ReadData = %v, %v, want nil", err, l.Errors())
+		}
+	}
+}
+
+func TestErrors(t *testing.T) {
+	l := NewBigEndianBuffer([]byte{0x01})
+	l.Field("a").Read16()
+	l.Field("b").Write24(0x1000000)
+	l.Field("c").Read8()
+
+	errs := l.Errors()
+	if len(errs) != 2 {
+		t.Fatalf("Errors() = %v, want 2 errors", errs)
+	}
+	if !errors.Is(errs[0], io.ErrUnexpectedEOF) || !errors.Is(errs[1], ErrOverflow) {
+		t.Errorf("Errors() = %v, want [%v %v]", errs, io.ErrUnexpectedEOF, ErrOverflow)
+	}
+	if err := l.Error(); err != errs[0] {
+		t.Errorf("Error() = %v, want first error %v", err, errs[0])
+	}
+
+	l = NewBigEndianBuffer(nil)
+	if errs := l.Errors(); len(errs) != 0 {
+		t.Errorf("Errors() = %v, want none", errs)
+	}
+}
+
+func TestAssertFinished(t *testing.T) {
+	l := NewBigEndianBuffer([]byte{0x01, 0x02, 0x03})
+	l.Read16()
+	if l.Finished() {
+		t.Errorf("Finished() = true with 1 byte left, want false")
+	}
+	l.AssertFinished()
+	if err := l.Error(); !errors.Is(err, ErrUnreadBytes) {
+		t.Errorf("AssertFinished(): Error() = %v, want %v", err, ErrUnreadBytes)
+	}
+
+	l = NewBigEndianBuffer([]byte{0x01, 0x02})
+	l.Read16()
+	if !l.Finished() {
+		t.Errorf("Finished() = false with no bytes left, want true")
+	}
+	l.AssertFinished()
+	if err := l.Error(); err != nil {
+		t.Errorf("AssertFinished(): Error() = %v, want nil", err)
+	}
+}
+
+type header struct {
+	Type uint8
+	Len  uint16
+}
+
+func (h *header) Marshal(l *Lexer) {
+	l.Write8(h.Type)
+	l.Write16(h.Len)
+}
+
+func (h *header) Unmarshal(l *Lexer) error {
+	h.Type = l.Read8()
+	h.Len = l.Read16()
+	return l.Error()
+}
+
+type name string
+
+func (n *name) Marshal(l *Lexer) {
+	l.WriteLengthPrefixedString8(string(*n))
+}
+
+func (n *name) Unmarshal(l *Lexer) error {
+	*n = name(l.ReadLengthPrefixedString8())
+	return nil
+}
+
+type errUnmarshaler struct {
+	called bool
+}
+
+func (e *errUnmarshaler) Unmarshal(l *Lexer) error {
+	e.called = true
+	return io.ErrNoProgress
+}
+
+func TestMarshalAll(t *testing.T) {
+	h := &header{Type: 1, Len: 0x0203}
+	n := name("foo")
+
+	l := NewBigEndianBuffer(nil)
+	MarshalAll(l, h, &n)
+	if got, want := l.Data(), []byte{0x01, 0x02, 0x03, 0x03, 'f', 'o', 'o'}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("MarshalAll() = %#v, want %#v", got, want)
+	}
+
+	var h2 header
+	var n2 name
+	if err := UnmarshalAll(l, &h2, &n2); err != nil {
+		t.Fatalf("UnmarshalAll() = %v, want nil", err)
+	}
+	if !reflect.DeepEqual(&h2, h) || n2 != n {
+		t.Errorf("UnmarshalAll() = %v, %q, want %v, %q", h2, n2, h, n)
+	}
+
+	// An error returned by Unmarshal stops UnmarshalAll.
+	e1, e2 := &errUnmarshaler{}, &errUnmarshaler{}
+	if err := UnmarshalAll(NewBigEndianBuffer(nil), e1, e2); err != io.ErrNoProgress {
+		t.Errorf("UnmarshalAll() = %v, want %v", err, io.ErrNoProgress)
+	}
+	if !e1.called || e2.called {
+		t.Errorf("UnmarshalAll() called = %v, %v, want true, false", e1.called, e2.called)
+	}
+
+	// So does an error only set in the Lexer.
+	e1 = &errUnmarshaler{}
+	if err := UnmarshalAll(NewBigEndianBuffer([]byte{0x05}), &n2, e1); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("UnmarshalAll() = %v, want %v", err, io.ErrUnexpectedEOF)
+	}
+	if e1.called {
+		t.Errorf("UnmarshalAll() continued after a Lexer error")
+	}
+}
+
+type errBinaryMarshaler struct{}
+
+func (errBinaryMarshaler) MarshalBinary() ([]byte, error) {
+	return []byte{0x01}, io.ErrNoProgress
+}
+
+func TestBinaryMarshaler(t *testing.T) {
+	ts := time.Date(2018, time.March, 1, 12, 30, 0, 0, time.UTC)
+	want, _ := ts.MarshalBinary()
+
+	l := NewBigEndianBuffer(nil)
+	WriteBinaryMarshaler(l, ts)
+	l.Write8(0xff)
+	if got := l.Data(); !reflect.DeepEqual(got[:len(got)-1], want) {
+		t.Fatalf("WriteBinaryMarshaler() wrote %#v, want %#v", got, want)
+	}
+
+	var got time.Time
+	ReadBinaryUnmarshaler(l, len(want), &got)
+	if err := l.Error(); err != nil || !got.Equal(ts) {
+		t.Errorf("ReadBinaryUnmarshaler() = %v, %v, want %v, nil", got, err, ts)
+	}
+
+	// The error of UnmarshalBinary is set, and the bytes are consumed.
+	l = NewBigEndianBuffer([]byte{0x00, 0x01, 0x02})
+	ReadBinaryUnmarshaler(l, 2, &got)
+	if err := l.Error(); err == nil || l.Len() != 1 {
+		t.Errorf("ReadBinaryUnmarshaler(bad time) = %v, Len() %d, want an error, Len() 1", err, l.Len())
+	}
+
+	l = NewBigEndianBuffer(nil)
+	WriteBinaryMarshaler(l, errBinaryMarshaler{})
+	if err := l.Error(); !errors.Is(err, io.ErrNoProgress) || l.Len() != 0 {
+		t.Errorf("WriteBinaryMarshaler() = %v, Len() %d, want %v, Len() 0", err, l.Len(), io.ErrNoProgress)
+	}
+}
+
+// tlv marshals a type and a length-prefixed, aligned body, using most of
+// the write helpers that look back at what was written.
+type tlv struct {
+	typ  uint8
+	body string
+}
+
+func (v *tlv) Marshal(l *Lexer) {
+	cp := l.Checkpoint()
+	l.WriteString("discarded")
+	l.Rollback(cp)
+
+	l.Write8(v.typ)
+	start := l.WriteMark()
+	l.Reserve(2)
+	l.WriteString(v.body)
+	l.PlaceLength16(start)
+	l.AlignWrite(4, 0)
+	csum := l.WriteChecksumPlaceholder()
+	l.PlaceInternetChecksum(start, csum)
+	l.WriteCRC32(crc32.IEEETable, 0)
+}
+
+func TestSizeOf(t *testing.T) {
+	for _, v := range []*tlv{
+		{typ: 1, body: ""},
+		{typ: 2, body: "hello"},
+		{typ: 3, body: "hello, world"},
+	} {
+		l := NewBigEndianBuffer(nil)
+		v.Marshal(l)
+		if err := l.Error(); err != nil {
+			t.Fatalf("Marshal(%v) = %v, want nil", v, err)
+		}
+		if got, want := SizeOf(v), l.Len(); got != want {
+			t.Errorf("SizeOf(%v) = %d, want %d", v, got, want)
+		}
+	}
+}
+
+func TestBufferSize(t *testing.T) {
+	l := NewBigEndianBufferSize(16)
+	if l.Len() != 0 || l.Cap() != 16 {
+		t.Errorf("NewBigEndianBufferSize(16): Len() = %d, Cap() = %d, want 0, 16", l.Len(), l.Cap())
+	}
+	l.Write64(1)
+	p := &l.Data()[0]
+	l.Write64(2)
+	if p != &l.Data()[0] {
+		t.Errorf("writing within the preallocated size reallocated the buffer")
+	}
+
+	l = NewLittleEndianBufferSize(0)
+	l.Write8(1)
+	l.Grow(100)
+	if l.Cap()-l.Len() < 100 {
+		t.Errorf("Grow(100): Cap() - Len() = %d, want >= 100", l.Cap()-l.Len())
+	}
+	if got, want := l.Data(), []byte{0x01}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Data() after Grow = %#v, want %#v", got, want)
+	}
+}
+
+func marshalHeader(l *Lexer) {
+	for i := 0; i < 64; i++ {
+		l.Write32(uint32(i))
+	}
+}
+
+func BenchmarkMarshalGrow(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		marshalHeader(NewBigEndianBuffer(nil))
+	}
+}
+
+func BenchmarkMarshalPresized(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		marshalHeader(NewBigEndianBufferSize(256))
+	}
+}
+
+func TestBufferString(t *testing.T) {
+	l := NewBigEndianBuffer([]byte("\x00uroot\x01\x02"))
+	l.Read8()
+	want := "00000000  75 72 6f 6f 74 01 02                              |uroot..|\n"
+	if got := l.String(); got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+	if l.Len() != 7 {
+		t.Errorf("Len() after String() = %d, want 7", l.Len())
+	}
+}
+
+func TestBufferDiff(t *testing.T) {
+	b := NewBuffer([]byte{0xff, 0x01, 0x02, 0x03})
+	b.ReadN(1)
+	for i, tt := range []struct {
+		other  []byte
+		offset int
+		equal  bool
+	}{
+		{other: []byte{0x01, 0x02, 0x03}, offset: 0, equal: true},
+		{other: []byte{0x01, 0x00, 0x03}, offset: 1, equal: false},
+		{other: []byte{0x01, 0x02}, offset: 2, equal: false},
+		{other: []byte{0x01, 0x02, 0x03, 0x04}, offset: 3, equal: false},
+		{other: nil, offset: 0, equal: false},
+	} {
+		offset, equal := b.Diff(tt.other)
+		if offset != tt.offset || equal != tt.equal {
+			t.Errorf("#%d: Diff(%#v) = %d, %v, want %d, %v", i, tt.other, offset, equal, tt.offset, tt.equal)
+		}
+		if got := b.Equal(tt.other); got != tt.equal {
+			t.Errorf("#%d: Equal(%#v) = %v, want %v", i, tt.other, got, tt.equal)
+		}
+	}
+}
+
+func TestBufferSafeReadN(t *testing.T) {
+	b := NewBuffer([]byte{0x01, 0x02, 0x03, 0x04})
+	alias, _ := b.ReadN(2)
+	safe, err := b.SafeReadN(2)
+	if err != nil || !reflect.DeepEqual(safe, []byte{0x03, 0x04}) {
+		t.Errorf("SafeReadN(2) = %#v, %v, want %#v, nil", safe, err, []byte{0x03, 0x04})
+	}
+
+	b.WriteAt([]byte{0xff, 0xff, 0xff, 0xff}, 0)
+	if want := []byte{0xff, 0xff}; !reflect.DeepEqual(alias, want) {
+		t.Errorf("ReadN(2) after WriteAt = %#v, want %#v", alias, want)
+	}
+	if want := []byte{0x03, 0x04}; !reflect.DeepEqual(safe, want) {
+		t.Errorf("SafeReadN(2) after WriteAt = %#v, want %#v", safe, want)
+	}
+
+	if p, err := b.SafeReadN(0); err != nil || p == nil || len(p) != 0 {
+		t.Errorf("SafeReadN(0) = %#v, %v, want empty slice, nil", p, err)
+	}
+	if _, err := b.SafeReadN(1); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("SafeReadN(1) = %v, want %v", err, io.ErrUnexpectedEOF)
+	}
+	if _, err := b.SafeReadN(-1); !errors.Is(err, ErrInvalidSize) {
+		t.Errorf("SafeReadN(-1) = %v, want %v", err, ErrInvalidSize)
+	}
+}
+
+func TestBufferBytes(t *testing.T) {
+	b := NewBuffer([]byte{0x01, 0x02, 0x03})
+	b.ReadN(1)
+	p := b.Bytes()
+	if want := []byte{0x02, 0x03}; !reflect.DeepEqual(p, want) {
+		t.Errorf("Bytes() = %#v, want %#v", p, want)
+	}
+	p[0] = 0xff
+	if got, want := b.Data(), []byte{0x02, 0x03}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Data() after changing Bytes() = %#v, want %#v", got, want)
+	}
+	b.Data()[0] = 0xfe
+	if want := []byte{0xff, 0x03}; !reflect.DeepEqual(p, want) {
+		t.Errorf("Bytes() after changing Data() = %#v, want %#v", p, want)
+	}
+
+	b.ReadN(2)
+	if p := b.Bytes(); p == nil || len(p) != 0 {
+		t.Errorf("Bytes() at the end = %#v, want empty slice", p)
+	}
+}
+
+func TestBufferTruncate(t *testing.T) {
+	b := NewBuffer([]byte{0x01, 0x02, 0x03, 0x04, 0x05})
+	b.ReadN(1)
+	if err := b.Truncate(2); err != nil {
+		t.Errorf("Truncate(2) = %v, want nil", err)
+	}
+	if got, want := b.Data(), []byte{0x02, 0x03}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Data() after Truncate(2) = %#v, want %#v", got, want)
+	}
+	copy(b.WriteN(1), []byte{0xff})
+	if got, want := b.Data(), []byte{0x02, 0x03, 0xff}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Data() after WriteN = %#v, want %#v", got, want)
+	}
+
+	for _, n := range []int{-1, 4} {
+		if err := b.Truncate(n); !errors.Is(err, ErrInvalidSize) {
+			t.Errorf("Truncate(%d) = %v, want %v", n, err, ErrInvalidSize)
+		}
+		l := NewLexer(b.Clone(), binary.BigEndian)
+		l.Truncate(n)
+		if err := l.Error(); !errors.Is(err, ErrInvalidSize) {
+			t.Errorf("Lexer.Truncate(%d): Error() = %v, want %v", n, err, ErrInvalidSize)
+		}
+	}
+	if got, want := b.Data(), []byte{0x02, 0x03, 0xff}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Data() after invalid Truncate = %#v, want %#v", got, want)
+	}
+}
+
+func TestBufferAppend(t *testing.T) {
+	b := NewBuffer([]byte{0xff, 0x01})
+	b.ReadN(1)
+	other := NewBuffer([]byte{0xfe, 0x02, 0x03})
+	other.ReadN(1)
+	b.Append(other)
+	b.Append(NewBuffer(nil))
+	if got, want := b.Data(), []byte{0x01, 0x02, 0x03}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Append() = %#v, want %#v", got, want)
+	}
+	if got, want := other.Data(), []byte{0x02, 0x03}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Append() consumed from other, Data() = %#v, want %#v", got, want)
+	}
+
+	b.Append(b)
+	if got, want := b.Data(), []byte{0x01, 0x02, 0x03, 0x01, 0x02, 0x03}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Append() to itself = %#v, want %#v", got, want)
+	}
+}
+
+func TestWriteBuffer(t *testing.T) {
+	payload := NewBigEndianBuffer(nil)
+	payload.WriteString("hello")
+	payload.Write16(0x0102)
+
+	header := NewBigEndianBuffer(nil)
+	header.Write8(0x01)
+	header.Write16(uint16(payload.Len()))
+
+	r := NewReaderLexer(iotest.OneByteReader(bytes.NewReader([]byte{0xaa, 0xbb})), binary.BigEndian)
+
+	frame := NewBigEndianBuffer(nil)
+	frame.WriteBuffer(header.Buffer)
+	frame.WriteBuffer(payload.Buffer)
+	frame.WriteBuffer(r.Buffer)
+	want := []byte{0x01, 0x00, 0x07, 'h', 'e', 'l', 'l', 'o', 0x01, 0x02, 0xaa, 0xbb}
+	if got := frame.Data(); !reflect.DeepEqual(got, want) {
+		t.Errorf("WriteBuffer() = %#v, want %#v", got, want)
+	}
+	if err := frame.Error(); err != nil {
+		t.Errorf("Error() = %v, want nil", err)
+	}
+}
+
+func TestWriteNBounds(t *testing.T) {
+	b := NewBuffer([]byte{0x01})
+	for _, n := range []int{-1, maxInt} {
+		if got := b.WriteN(n); got != nil {
+			t.Errorf("WriteN(%d) = %d bytes, want nil", n, len(got))
+		}
+	}
+	if got, want := b.Data(), []byte{0x01}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Data() = %#v, want %#v", got, want)
+	}
+
+	for i, tt := range []struct {
+		maxSize int
+		write   func(l *Lexer)
+		want    error
+	}{
+		{write: func(l *Lexer) { l.Append(-1) }, want: ErrInvalidSize},
+		{write: func(l *Lexer) { l.Reserve(maxInt) }, want: ErrTooLong},
+		{maxSize: 1 << 20, write: func(l *Lexer) { l.WriteRepeat(0xff, maxInt-1) }, want: ErrTooLong},
+		{maxSize: 1 << 20, write: func(l *Lexer) { l.WriteZeroes(1<<20 + 1) }, want: ErrTooLong},
+		{maxSize: 4, write: func(l *Lexer) { l.Write32(1) }, want: ErrTooLong},
+		{maxSize: 4, write: func(l *Lexer) { l.Write16N([]uint16{1, 2}) }, want: ErrTooLong},
+		{maxSize: 4, write: func(l *Lexer) { l.WriteUTF16Z("a") }, want: ErrTooLong},
+		{maxSize: 4, write: func(l *Lexer) { l.WriteFixedString("a", 4, 0) }, want: ErrTooLong},
+		{maxSize: 4, write: func(l *Lexer) { l.Write24(1) }},
+	} {
+		l := NewBigEndianBuffer([]byte{0x01})
+		l.SetMaxSize(tt.maxSize)
+		before := l.Len()
+		tt.write(l)
+		if err := l.Error(); !errors.Is(err, tt.want) {
+			t.Errorf("#%d: Error() = %v, want %v", i, err, tt.want)
+		}
+		if tt.want != nil && l.Len() != before {
+			t.Errorf("#%d: Len() = %d, want %d", i, l.Len(), before)
+		}
+	}
+
+	l := NewBigEndianBuffer(nil)
+	l.SetMaxSize(2)
+	if n, err := l.Write([]byte{1, 2, 3}); n != 0 || !errors.Is(err, ErrTooLong) {
+		t.Errorf("Write() = %d, %v, want 0, %v", n, err, ErrTooLong)
+	}
+}
+
+func TestBufferReadWriteAt(t *testing.T) {
+	b := NewBuffer([]byte{0x01, 0x02, 0x03, 0x04})
+	b.ReadN(2)
+
+	p := make([]byte, 2)
+	if n, err := b.ReadAt(p, 1); n != 2 || err != nil || !reflect.DeepEqual(p, []byte{0x02, 0x03}) {
+		t.Errorf("ReadAt(1) = %d, %v, %#v, want 2, nil, %#v", n, err, p, []byte{0x02, 0x03})
+	}
+	if n, err := b.ReadAt(p, 3); n != 1 || err != io.EOF {
+		t.Errorf("ReadAt(3) = %d, %v, want 1, %v", n, err, io.EOF)
+	}
+	if _, err := b.ReadAt(p, -1); !errors.Is(err, ErrInvalidOffset) {
+		t.Errorf("ReadAt(-1) = %v, want %v", err, ErrInvalidOffset)
+	}
+
+	if n, err := b.WriteAt([]byte{0xaa}, 0); n != 1 || err != nil {
+		t.Errorf("WriteAt(0) = %d, %v, want 1, nil", n, err)
+	}
+	if n, err := b.WriteAt([]byte{0xbb, 0xcc}, 5); n != 2 || err != nil {
+		t.Errorf("WriteAt(5) = %d, %v, want 2, nil", n, err)
+	}
+	if b.Tell() != 2 {
+		t.Errorf("Tell() after WriteAt = %d, want 2", b.Tell())
+	}
+	if got, want := b.Data(), []byte{0x03, 0x04, 0x00, 0xbb, 0xcc}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Data() after WriteAt = %#v, want %#v", got, want)
+	}
+	if _, err := b.ReadAt(p, 0); err != nil || p[0] != 0xaa {
+		t.Errorf("ReadAt(0) = %#v, %v, want 0xaa, nil", p[0], err)
+	}
+
+	// Growing into a reused array must not expose stale bytes.
+	b = NewBuffer([]byte{0x01, 0x02, 0x03})
+	b.Truncate(0)
+	b.WriteAt([]byte{0xff}, 2)
+	if got, want := b.Data(), []byte{0x00, 0x00, 0xff}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Data() after WriteAt = %#v, want %#v", got, want)
+	}
+}
+
+func TestSetMaxAlloc(t *testing.T) {
+	// A 0xffffffff length prefix, as a hostile packet might contain.
+	hostile := []byte{0xff, 0xff, 0xff, 0xff, 0x01, 0x02, 0x03}
+	// With a 32-bit int, the converted length is negative.
+	converted := ErrTooLong
+	if maxInt == math.MaxInt32 {
+		converted = ErrInvalidSize
+	}
+	for _, tt := range []struct {
+		name string
+		read func(l *Lexer) interface{}
+		want error
+	}{
+		{"ReadLengthPrefixed32", func(l *Lexer) interface{} { return l.ReadLengthPrefixed32() }, ErrTooLong},
+		{"CopyN", func(l *Lexer) interface{} { return l.CopyN(int(l.Read32())) }, converted},
+		{"Read16N", func(l *Lexer) interface{} { return l.Read16N(int(l.Read32())) }, converted},
+	} {
+		l := NewReaderLexer(bytes.NewReader(hostile), binary.LittleEndian)
+		l.SetMaxAlloc(1024)
+		if got := tt.read(l); !reflect.ValueOf(got).IsNil() {
+			t.Errorf("%s() = %#v, want nil", tt.name, got)
+		}
+		if err := l.Error(); !errors.Is(err, tt.want) {
+			t.Errorf("%s(): Error() = %v, want %v", tt.name, err, tt.want)
+		}
+		if got, want := l.Tell(), 4; got != want {
+			t.Errorf("%s(): Tell() = %d, want %d", tt.name, got, want)
+		}
+	}
+
+	// count*8 wraps around to 0 with a 64-bit int.
+	huge := int(uint64(maxInt)>>1 + 1)
+	l := NewBigEndianBuffer(hostile)
+	l.SetMaxAlloc(100)
+	if got := l.Read64N(huge); got != nil {
+		t.Errorf("Read64N(%d) = %#v, want nil", huge, got)
+	}
+	if err := l.Error(); !errors.Is(err, ErrTooLong) {
+		t.Errorf("Read64N(%d): Error() = %v, want %v", huge, err, ErrTooLong)
+	}
+
+	l = NewBigEndianBuffer([]byte{0x01, 0x02, 0x03})
+	l.SetMaxAlloc(2)
+	if got := l.ReadAll(); got != nil {
+		t.Errorf("ReadAll() = %#v, want nil", got)
+	}
+	if err := l.Error(); !errors.Is(err, ErrTooLong) {
+		t.Errorf("ReadAll(): Error() = %v, want %v", err, ErrTooLong)
+	}
+	l.SetMaxAlloc(0)
+	if got, want := l.ReadAll(), []byte{0x01, 0x02, 0x03}; !reflect.DeepEqual(got, want) {
+		t.Errorf("ReadAll() without a limit = %#v, want %#v", got, want)
+	}
+}
+
+func TestReadAllInto(t *testing.T) {
+	dst := make([]byte, 4)
+	for i, tt := range []struct {
+		l    *Lexer
+		want [][]byte
+	}{
+		{l: NewBigEndianBuffer([]byte("0123456789")), want: [][]byte{[]byte("0123"), []byte("4567"), []byte("89"), {}}},
+		{l: NewBigEndianBuffer([]byte("0123")), want: [][]byte{[]byte("0123"), {}}},
+		{l: NewBigEndianBuffer(nil), want: [][]byte{{}}},
+		{l: NewReaderLexer(iotest.OneByteReader(bytes.NewReader([]byte("012345"))), binary.BigEndian), want: [][]byte{[]byte("0123"), []byte("45"), {}}},
+	} {
+		for j, want := range tt.want {
+			n := tt.l.ReadAllInto(dst)
+			if got := dst[:n]; !reflect.DeepEqual(got, want) {
+				t.Errorf("#%d: ReadAllInto() call %d = %q, want %q", i, j, got, want)
+			}
+		}
+		if !tt.l.Finished() {
+			t.Errorf("#%d: Finished() = false, want true", i)
+		}
+		if err := tt.l.Error(); err != nil {
+			t.Errorf("#%d: Error() = %v, want nil", i, err)
+		}
+	}
+}
+
+func TestClone(t *testing.T) {
+	l := NewBigEndianBuffer([]byte{0x01, 0x02, 0x03, 0x04})
+	l.Read8()
+	l.Field("x").Skip(5)
+
+	c := l.Clone()
+	if c.Tell() != l.Tell() || c.Order() != l.Order() || !reflect.DeepEqual(c.Errors(), l.Errors()) {
+		t.Errorf("Clone() = %d, %v, %v, want %d, %v, %v", c.Tell(), c.Order(), c.Errors(), l.Tell(), l.Order(), l.Errors())
+	}
+
+	c.Rewind(0)
+	c.Write8(0x05)
+	c.Data()[0] = 0xff
+	c.SetOrder(binary.LittleEndian)
+	c.Skip(10)
+	if got, want := l.Data(), []byte{0x02, 0x03, 0x04}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Data() after changing the clone = %#v, want %#v", got, want)
+	}
+	if l.Order() != binary.BigEndian || len(l.Errors()) != 1 {
+		t.Errorf("Order(), Errors() after changing the clone = %v, %v, want %v and one error", l.Order(), l.Errors(), binary.BigEndian)
+	}
+	if got, want := c.Buffer.data, []byte{0xff, 0x02, 0x03, 0x04, 0x05}; !reflect.DeepEqual(got, want) {
+		t.Errorf("clone's data = %#v, want %#v", got, want)
+	}
+}
+
+func TestBufferSeek(t *testing.T) {
+	b := NewBuffer([]byte{0x01, 0x02, 0x03, 0x04, 0x05})
+	for i, tt := range []struct {
+		offset int64
+		whence int
+		want   int64
+		err    error
+	}{
+		{offset: 2, whence: io.SeekStart, want: 2},
+		{offset: 1, whence: io.SeekCurrent, want: 3},
+		{offset: -1, whence: io.SeekEnd, want: 4},
+		{offset: -4, whence: io.SeekCurrent, want: 0},
+		{offset: 0, whence: io.SeekEnd, want: 5},
+		{offset: -1, whence: io.SeekStart, err: ErrInvalidOffset},
+		{offset: 1, whence: io.SeekEnd, err: ErrInvalidOffset},
+		{offset: 0, whence: 42, err: ErrInvalidOffset},
+	} {
+		got, err := b.Seek(tt.offset, tt.whence)
+		if !errors.Is(err, tt.err) || (err == nil && got != tt.want) {
+			t.Errorf("#%d: Seek(%d, %d) = %d, %v, want %d, %v", i, tt.offset, tt.whence, got, err, tt.want, tt.err)
+		}
+		if err == nil && b.Tell() != int(tt.want) {
+			t.Errorf("#%d: Tell() = %d, want %d", i, b.Tell(), tt.want)
+		}
+	}
+	if b.Tell() != 5 {
+		t.Errorf("Tell() after failed seeks = %d, want 5", b.Tell())
+	}
+
+	// A Lexer is an io.ReadSeeker.
+	var rs io.ReadSeeker = NewBigEndianBuffer([]byte("hello"))
+	rs.Seek(-2, io.SeekEnd)
+	p := make([]byte, 2)
+	if _, err := io.ReadFull(rs, p); string(p) != "lo" || err != nil {
+		t.Errorf("ReadFull() after Seek(-2, io.SeekEnd) = %q, %v, want %q, nil", p, err, "lo")
+	}
+
+	l := NewReaderLexer(bytes.NewReader([]byte("hello")), binary.BigEndian)
+	if pos, err := l.Seek(3, io.SeekStart); pos != 3 || err != nil {
+		t.Errorf("Seek(3) on a reader Lexer = %d, %v, want 3, nil", pos, err)
+	}
+	if got, want := l.ReadString(2), "lo"; got != want {
+		t.Errorf("ReadString(2) = %q, want %q", got, want)
+	}
+}
+
+func TestCountingLexer(t *testing.T) {
+	l := NewCountingLexer(binary.LittleEndian)
+	l.Write32(0x01020304)
+	l.WriteZeroes(1 << 20)
+	l.WriteN(3)
+	if got, want := l.Len(), 4+1<<20+3; got != want {
+		t.Errorf("Len() = %d, want %d", got, want)
+	}
+	if len(l.Data()) != 0 {
+		t.Errorf("Data() = %d bytes, want none", len(l.Data()))
+	}
+	if err := l.Error(); err != nil {
+		t.Errorf("Error() = %v, want nil", err)
+	}
+
+	if got := l.Read8(); got != 0 {
+		t.Errorf("Read8() = %#x, want 0", got)
+	}
+	if err := l.Error(); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("Read8(): Error() = %v, want %v", err, io.ErrUnexpectedEOF)
+	}
+
+	l.Reset()
+	if l.Len() != 0 {
+		t.Errorf("Len() after Reset = %d, want 0", l.Len())
+	}
+}
+
+func TestWriteData(t *testing.T) {
+	l := NewLittleEndianBuffer(nil)
+	l.WriteData(struct {
+		A uint16
+		B int32
+	}{A: 1, B: -2})
+	l.WriteData([]uint16{3, 4})
+	want := []byte{0x01, 0x00, 0xfe, 0xff, 0xff, 0xff, 0x03, 0x00, 0x04, 0x00}
+	if got := l.Data(); !reflect.DeepEqual(got, want) {
+		t.Errorf("WriteData() = %#v, want %#v", got, want)
+	}
+	if err := l.Error(); err != nil {
+		t.Errorf("Error() = %v, want nil", err)
+	}
+
+	for _, tt := range []struct {
+		data interface{}
+		name string
+	}{
+		{data: 1, name: "int"},
+		{data: "foo", name: "string"},
+		{data: []*uint32{new(uint32)}, name: "[]*uint32"},
+	} {
+		l := NewLittleEndianBuffer(nil)
+		l.WriteData(tt.data)
+		err := l.Error()
+		if !errors.Is(err, ErrUnsupportedType) {
+			t.Errorf("WriteData(%s): Error() = %v, want %v", tt.name, err, ErrUnsupportedType)
+		} else if !strings.Contains(err.Error(), tt.name) {
+			t.Errorf("WriteData(%s): Error() = %v, want it to name %s", tt.name, err, tt.name)
+		}
+		if l.Len() != 0 {
+			t.Errorf("WriteData(%s) wrote %d bytes, want 0", tt.name, l.Len())
+		}
+	}
+}
+
+func TestHasStruct(t *testing.T) {
+	type rec struct {
+		A uint16
+		B [2]uint8
+	}
+
+	// Data arriving in pieces, appended to the Buffer as it comes.
+	l := NewBigEndianBuffer(nil)
+	var got rec
+	for _, p := range [][]byte{{0x01}, {0x02, 0x03}} {
+		l.WriteBytes(p)
+		if l.HasStruct(&got) {
+			t.Errorf("HasStruct() with %d bytes = true, want false", l.Len())
+		}
+	}
+	l.Write8(0x04)
+	if !l.HasStruct(&got) {
+		t.Errorf("HasStruct() with 4 bytes = false, want true")
+	}
+	if l.Len() != 4 {
+		t.Errorf("Len() after HasStruct = %d, want 4", l.Len())
+	}
+	l.ReadData(&got)
+	if want := (rec{A: 0x0102, B: [2]uint8{0x03, 0x04}}); got != want {
+		t.Errorf("ReadData() = %#v, want %#v", got, want)
+	}
+	if l.HasStruct(&got) {
+		t.Errorf("HasStruct() at EOF = true, want false")
+	}
+	if err := l.Error(); err != nil {
+		t.Errorf("Error() = %v, want nil", err)
+	}
+
+	var m map[string]int
+	if l.HasStruct(&m) {
+		t.Errorf("HasStruct(map) = true, want false")
+	}
+	if err := l.Error(); !errors.Is(err, ErrUnsupportedType) {
+		t.Errorf("HasStruct(map): Error() = %v, want %v", err, ErrUnsupportedType)
+	}
+}
+
+func TestReadData(t *testing.T) {
+	type rec struct {
+		A uint16
+		B [2]uint8
+	}
+
+	l := NewBigEndianBuffer([]byte{0x01, 0x02, 0x03, 0x04, 0x05, 0x06})
+	var r rec
+	l.ReadData(&r)
+	if want := (rec{A: 0x0102, B: [2]uint8{0x03, 0x04}}); r != want {
+		t.Errorf("ReadData() = %#v, want %#v", r, want)
+	}
+	if err := l.Error(); err != nil {
+		t.Errorf("Error() = %v, want nil", err)
+	}
+
+	// Too large a target fails up front, without consuming anything.
+	s := make([]uint32, 1)
+	l.ReadData(s)
+	if err := l.Error(); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("ReadData([]uint32{1}): Error() = %v, want %v", err, io.ErrUnexpectedEOF)
+	}
+	if l.Len() != 2 {
+		t.Errorf("Len() after failed ReadData = %d, want 2", l.Len())
+	}
+
+	l = NewBigEndianBuffer([]byte{0x01, 0x02})
+	var m map[string]int
+	l.ReadData(&m)
+	if err := l.Error(); !errors.Is(err, ErrUnsupportedType) {
+		t.Errorf("ReadData(map): Error() = %v, want %v", err, ErrUnsupportedType)
+	}
+	if l.Len() != 2 {
+		t.Errorf("Len() after failed ReadData = %d, want 2", l.Len())
+	}
+}
@@ -0,0 +1,127 @@
+// Copyright 2018 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package uio
+
+import (
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+)
+
+// ErrChecksum is set when a checksum read from the buffer does not match the
+// data it covers.
+var ErrChecksum = errors.New("checksum mismatch")
+
+// internetChecksum computes the RFC 1071 checksum of p. An odd final byte is
+// padded with a zero byte.
+func internetChecksum(p []byte) uint16 {
+	// A uint64 cannot overflow before 2^48 words, unlike a uint32, which
+	// would after 64K words.
+	var sum uint64
+	for ; len(p) >= 2; p = p[2:] {
+		sum += uint64(p[0])<<8 | uint64(p[1])
+	}
+	if len(p) == 1 {
+		sum += uint64(p[0]) << 8
+	}
+	for sum > 0xffff {
+		sum = sum&0xffff + sum>>16
+	}
+	return ^uint16(sum)
+}
+
+// InternetChecksum returns the RFC 1071 internet checksum of the unconsumed
+// data, as used by IP, UDP, TCP and ICMP.
+func (b *Buffer) InternetChecksum() uint16 {
+	return internetChecksum(b.Data())
+}
+
+// WriteChecksumPlaceholder appends a zeroed 16-bit checksum field and returns
+// its write position, as returned by WriteMark, to be passed to
+// PlaceInternetChecksum.
+func (l *Lexer) WriteChecksumPlaceholder() int {
+	field := l.WriteMark()
+	l.Reserve(2)
+	return field
+}
+
+// PlaceInternetChecksum computes the internet checksum of the bytes written
+// since start and stores it, in network byte order, in the zeroed checksum
+// field at field. Both are write positions, as returned by WriteMark.
+//
+// Use:
+//
+//	start := l.WriteMark()
+//	l.Write16(0x4500)
+//	...
+//	csum := l.WriteChecksumPlaceholder()
+//	...
+//	l.PlaceInternetChecksum(start, csum)
+//
+// If field is not within the checksummed region, Error() will return
+// ErrInvalidOffset.
+func (l *Lexer) PlaceInternetChecksum(start, field int) {
+	if field < start || field > l.writeEnd()-2 {
+		l.setError(ErrInvalidOffset)
+		return
+	}
+	start, ok := l.writeIndex(start)
+	if !ok || l.counting {
+		return
+	}
+	field -= l.writeBase()
+	binary.BigEndian.PutUint16(l.data[field:], internetChecksum(l.data[start:]))
+}
+
+// CRC32 returns the CRC-32 of the bytes read since the read position mark,
+// as returned by Mark, using tab, e.g. crc32.IEEETable or crc32.MakeTable(crc32.Castagnoli).
+//
+// If mark is not a valid position before the read position, Error() will
+// return ErrInvalidOffset.
+func (l *Lexer) CRC32(tab *crc32.Table, mark int) uint32 {
+	if mark < 0 || mark > l.off {
+		l.setError(ErrInvalidOffset)
+		return 0
+	}
+	return crc32.Checksum(l.data[mark:l.off], tab)
+}
+
+// WriteCRC32 appends the CRC-32 of the bytes written since the write position
+// start, as returned by WriteMark, computed using tab, as a 32-bit value in
+// the Lexer's byte order.
+//
+// If an error occurred, Error() will return a non-nil error.
+func (l *Lexer) WriteCRC32(tab *crc32.Table, start int) {
+	i, ok := l.writeIndex(start)
+	if !ok {
+		return
+	}
+	if l.counting {
+		l.Write32(0)
+		return
+	}
+	l.Write32(crc32.Checksum(l.data[i:], tab))
+}
+
+// CheckCRC32 reads a 32-bit CRC in the Lexer's byte order and compares it
+// with the CRC-32 of the bytes read since mark, computed using tab.
+//
+// Use:
+//
+//	mark := l.Mark()
+//	typ := l.Read32()
+//	data := l.CopyN(n)
+//	l.CheckCRC32(crc32.IEEETable, mark)
+//
+// The CRC is consumed even if an error occurred. If the CRCs do not match,
+// Error() will return ErrChecksum.
+func (l *Lexer) CheckCRC32(tab *crc32.Table, mark int) {
+	errs := len(l.errs)
+	want := l.CRC32(tab, mark)
+	got := l.Read32()
+	if len(l.errs) == errs && got != want {
+		l.setError(ErrChecksum)
+	}
+}
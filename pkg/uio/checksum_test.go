@@ -0,0 +1,157 @@
+// Copyright 2018 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package uio
+
+import (
+	"bytes"
+	"errors"
+	"hash/crc32"
+	"reflect"
+	"testing"
+)
+
+func TestInternetChecksum(t *testing.T) {
+	for i, tt := range []struct {
+		in   []byte
+		want uint16
+	}{
+		{
+			// RFC 1071, section 3.
+			in:   []byte{0x00, 0x01, 0xf2, 0x03, 0xf4, 0xf5, 0xf6, 0xf7},
+			want: ^uint16(0xddf2),
+		},
+		{
+			// Odd length: the last byte is padded with zero.
+			in:   []byte{0x00, 0x01, 0xf2},
+			want: ^uint16(0xf201),
+		},
+		{
+			in:   nil,
+			want: 0xffff,
+		},
+		{
+			// More 0xffff words than a uint32 sum can hold.
+			in:   bytes.Repeat([]byte{0xff}, 200000),
+			want: 0x0000,
+		},
+	} {
+		if got := NewBuffer(tt.in).InternetChecksum(); got != tt.want {
+			t.Errorf("#%d: InternetChecksum() = %#04x, want %#04x", i, got, tt.want)
+		}
+	}
+}
+
+func TestPlaceInternetChecksum(t *testing.T) {
+	// Positions count consumed bytes too.
+	l := NewLittleEndianBuffer([]byte{0xff})
+	l.Read8()
+
+	// An IPv4 header, with its checksum computed in place.
+	start := l.WriteMark()
+	l.WriteBytes([]byte{0x45, 0x00, 0x00, 0x73, 0x00, 0x00, 0x40, 0x00, 0x40, 0x11})
+	csum := l.WriteChecksumPlaceholder()
+	l.WriteBytes([]byte{0xc0, 0xa8, 0x00, 0x01, 0xc0, 0xa8, 0x00, 0xc7})
+	l.PlaceInternetChecksum(start, csum)
+
+	if err := l.Error(); err != nil {
+		t.Fatalf("Error() = %v, want nil", err)
+	}
+	if got, want := l.RegionSince(csum)[:2], []byte{0xb8, 0x61}; !reflect.DeepEqual(got, want) {
+		t.Errorf("checksum = %#v, want %#v", got, want)
+	}
+	if got := l.InternetChecksum(); got != 0 {
+		t.Errorf("InternetChecksum() of a checksummed header = %#04x, want 0", got)
+	}
+
+	l.PlaceInternetChecksum(5, 3)
+	if err := l.Error(); !errors.Is(err, ErrInvalidOffset) {
+		t.Errorf("PlaceInternetChecksum(5, 3): Error() = %v, want %v", err, ErrInvalidOffset)
+	}
+}
+
+// A PNG IHDR chunk for a 1x1 RGBA image, followed by an IEND chunk.
+var pngChunks = []byte{
+	0x00, 0x00, 0x00, 0x0d, 'I', 'H', 'D', 'R',
+	0x00, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00, 0x01, 0x08, 0x06, 0x00, 0x00, 0x00,
+	0x1f, 0x15, 0xc4, 0x89,
+	0x00, 0x00, 0x00, 0x00, 'I', 'E', 'N', 'D',
+	0xae, 0x42, 0x60, 0x82,
+}
+
+func TestCheckCRC32(t *testing.T) {
+	l := NewBigEndianBuffer(pngChunks)
+	for _, want := range []string{"IHDR", "IEND"} {
+		n := l.Read32()
+		mark := l.Mark()
+		typ := string(l.CopyN(4))
+		l.Skip(int(n))
+		if got := l.CRC32(crc32.IEEETable, mark); l.Error() != nil || got != l.Peek32() {
+			t.Errorf("%s: CRC32() = %#08x, want %#08x", want, got, l.Peek32())
+		}
+		l.CheckCRC32(crc32.IEEETable, mark)
+		if typ != want {
+			t.Errorf("chunk type = %q, want %q", typ, want)
+		}
+	}
+	l.AssertFinished()
+	if err := l.Error(); err != nil {
+		t.Errorf("Error() = %v, want nil", err)
+	}
+
+	corrupt := append([]byte(nil), pngChunks...)
+	corrupt[12] ^= 1
+	l = NewBigEndianBuffer(corrupt)
+	mark := l.Mark() + 4
+	l.Skip(4 + 4 + 13)
+	l.CheckCRC32(crc32.IEEETable, mark)
+	if err := l.Error(); !errors.Is(err, ErrChecksum) {
+		t.Errorf("CheckCRC32() on corrupt chunk: Error() = %v, want %v", err, ErrChecksum)
+	}
+
+	// An earlier, unrelated error neither skips the check nor leaves the
+	// CRC unread.
+	l = NewBigEndianBuffer(corrupt)
+	l.ReadEnum32(0)
+	mark = l.Mark()
+	l.Skip(4 + 13)
+	l.CheckCRC32(crc32.IEEETable, mark)
+	if errs := l.Errors(); len(errs) != 2 || !errors.Is(errs[1], ErrChecksum) {
+		t.Errorf("CheckCRC32() after an error: Errors() = %v, want a second error %v", errs, ErrChecksum)
+	}
+	if got, want := l.Tell(), 25; got != want {
+		t.Errorf("Tell() after CheckCRC32() = %d, want %d", got, want)
+	}
+}
+
+func TestWriteCRC32(t *testing.T) {
+	l := NewBigEndianBuffer(nil)
+	for _, c := range []struct {
+		typ  string
+		data []byte
+	}{
+		{"IHDR", []byte{0x00, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00, 0x01, 0x08, 0x06, 0x00, 0x00, 0x00}},
+		{"IEND", nil},
+	} {
+		l.Write32(uint32(len(c.data)))
+		start := l.WriteMark()
+		l.WriteBytes([]byte(c.typ))
+		l.WriteBytes(c.data)
+		l.WriteCRC32(crc32.IEEETable, start)
+	}
+	if err := l.Error(); err != nil {
+		t.Fatalf("Error() = %v, want nil", err)
+	}
+	if got := l.Data(); !reflect.DeepEqual(got, pngChunks) {
+		t.Errorf("Data() = %#v, want %#v", got, pngChunks)
+	}
+
+	// Castagnoli, check value from RFC 3720, B.4: 32 bytes of zeroes.
+	l = NewLittleEndianBuffer(nil)
+	l.WriteBytes(make([]byte, 32))
+	l.WriteCRC32(crc32.MakeTable(crc32.Castagnoli), 0)
+	if got, want := l.Data()[32:], []byte{0xaa, 0x36, 0x91, 0x8a}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Castagnoli CRC = %#v, want %#v", got, want)
+	}
+}
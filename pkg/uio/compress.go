@@ -0,0 +1,40 @@
+// Copyright 2018 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package uio
+
+import (
+	"compress/gzip"
+	"compress/zlib"
+	"encoding/binary"
+	"io"
+)
+
+// NewGzipLexer returns a Lexer that lazily reads and decompresses gzip data
+// from r, like NewReaderLexer.
+//
+// The gzip header is read immediately. If r does not start with one,
+// NewGzipLexer returns gzip.ErrHeader. Later decompression errors, such as
+// gzip.ErrChecksum, become the Lexer's error.
+func NewGzipLexer(r io.Reader, order binary.ByteOrder) (*Lexer, error) {
+	zr, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	return NewReaderLexer(zr, order), nil
+}
+
+// NewZlibLexer returns a Lexer that lazily reads and decompresses zlib data
+// from r, like NewReaderLexer.
+//
+// The zlib header is read immediately. If r does not start with one,
+// NewZlibLexer returns zlib.ErrHeader. Later decompression errors, such as
+// zlib.ErrChecksum, become the Lexer's error.
+func NewZlibLexer(r io.Reader, order binary.ByteOrder) (*Lexer, error) {
+	zr, err := zlib.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	return NewReaderLexer(zr, order), nil
+}
@@ -0,0 +1,70 @@
+// Copyright 2018 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package uio
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"encoding/binary"
+	"errors"
+	"io"
+	"testing"
+)
+
+func TestGzipLexer(t *testing.T) {
+	var z bytes.Buffer
+	w := gzip.NewWriter(&z)
+	w.Write([]byte("\x07\x00070701initramfs"))
+	w.Close()
+
+	l, err := NewGzipLexer(bytes.NewReader(z.Bytes()), binary.LittleEndian)
+	if err != nil {
+		t.Fatalf("NewGzipLexer() = %v, want nil", err)
+	}
+	if got, want := l.Read16(), uint16(7); got != want {
+		t.Errorf("Read16() = %#x, want %#x", got, want)
+	}
+	if got, want := string(l.ReadAll()), "070701initramfs"; got != want {
+		t.Errorf("ReadAll() = %q, want %q", got, want)
+	}
+	if err := l.Error(); err != nil {
+		t.Errorf("Error() = %v, want nil", err)
+	}
+
+	if _, err := NewGzipLexer(bytes.NewReader([]byte("not gzip data")), binary.LittleEndian); !errors.Is(err, gzip.ErrHeader) {
+		t.Errorf("NewGzipLexer(non-gzip) = %v, want %v", err, gzip.ErrHeader)
+	}
+
+	// A truncated stream fails when the data is read.
+	l, err = NewGzipLexer(bytes.NewReader(z.Bytes()[:z.Len()-4]), binary.LittleEndian)
+	if err != nil {
+		t.Fatalf("NewGzipLexer(truncated) = %v, want nil", err)
+	}
+	l.ReadAll()
+	l.Read8()
+	if err := l.Error(); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("Error() = %v, want %v", err, io.ErrUnexpectedEOF)
+	}
+}
+
+func TestZlibLexer(t *testing.T) {
+	var z bytes.Buffer
+	w := zlib.NewWriter(&z)
+	w.Write([]byte{0x01, 0x02, 0x03, 0x04})
+	w.Close()
+
+	l, err := NewZlibLexer(&z, binary.BigEndian)
+	if err != nil {
+		t.Fatalf("NewZlibLexer() = %v, want nil", err)
+	}
+	if got, want := l.Read32(), uint32(0x01020304); got != want {
+		t.Errorf("Read32() = %#x, want %#x", got, want)
+	}
+
+	if _, err := NewZlibLexer(bytes.NewReader([]byte{0x00, 0x00}), binary.BigEndian); !errors.Is(err, zlib.ErrHeader) {
+		t.Errorf("NewZlibLexer(non-zlib) = %v, want %v", err, zlib.ErrHeader)
+	}
+}
@@ -0,0 +1,42 @@
+// Copyright 2018 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package uio
+
+import (
+	"encoding/base32"
+	"encoding/base64"
+)
+
+// Base64String returns the standard, padded base64 encoding of the
+// unconsumed data.
+func (b *Buffer) Base64String() string {
+	return base64.StdEncoding.EncodeToString(b.Data())
+}
+
+// NewBufferFromBase64 returns a new Buffer holding the data decoded from the
+// standard, padded base64 string s.
+func NewBufferFromBase64(s string) (*Buffer, error) {
+	data, err := base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	return NewBuffer(data), nil
+}
+
+// Base32String returns the standard, padded base32 encoding of the
+// unconsumed data.
+func (b *Buffer) Base32String() string {
+	return base32.StdEncoding.EncodeToString(b.Data())
+}
+
+// NewBufferFromBase32 returns a new Buffer holding the data decoded from the
+// standard, padded base32 string s.
+func NewBufferFromBase32(s string) (*Buffer, error) {
+	data, err := base32.StdEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	return NewBuffer(data), nil
+}
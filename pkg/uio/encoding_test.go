@@ -0,0 +1,49 @@
+// Copyright 2018 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package uio
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestBase64(t *testing.T) {
+	b := NewBuffer([]byte("\xffhello"))
+	b.ReadN(1)
+	if got, want := b.Base64String(), "aGVsbG8="; got != want {
+		t.Errorf("Base64String() = %q, want %q", got, want)
+	}
+
+	b, err := NewBufferFromBase64("aGVsbG8=")
+	if err != nil {
+		t.Fatalf("NewBufferFromBase64() = %v, want nil", err)
+	}
+	if got, want := b.Data(), []byte("hello"); !reflect.DeepEqual(got, want) {
+		t.Errorf("Data() = %q, want %q", got, want)
+	}
+
+	if _, err := NewBufferFromBase64("aGVsbG8"); err == nil {
+		t.Errorf("NewBufferFromBase64(unpadded) = nil, want an error")
+	}
+}
+
+func TestBase32(t *testing.T) {
+	b := NewBuffer([]byte("hello"))
+	if got, want := b.Base32String(), "NBSWY3DP"; got != want {
+		t.Errorf("Base32String() = %q, want %q", got, want)
+	}
+
+	b, err := NewBufferFromBase32("NBSWY3DP")
+	if err != nil {
+		t.Fatalf("NewBufferFromBase32() = %v, want nil", err)
+	}
+	if got, want := b.Data(), []byte("hello"); !reflect.DeepEqual(got, want) {
+		t.Errorf("Data() = %q, want %q", got, want)
+	}
+
+	if _, err := NewBufferFromBase32("nbswy3dp"); err == nil {
+		t.Errorf("NewBufferFromBase32(lower case) = nil, want an error")
+	}
+}
@@ -0,0 +1,177 @@
+// Copyright 2018 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package uio
+
+import (
+	"encoding/binary"
+)
+
+// Read16LE reads a 16-bit little endian value, regardless of the Lexer's
+// byte order, which is left unchanged.
+//
+// If an error occurred, Error() will return a non-nil error.
+func (l *Lexer) Read16LE() uint16 {
+	v := l.consume(2)
+	if v == nil {
+		return 0
+	}
+	return binary.LittleEndian.Uint16(v)
+}
+
+// Read16BE reads a 16-bit big endian value, regardless of the Lexer's
+// byte order, which is left unchanged.
+//
+// If an error occurred, Error() will return a non-nil error.
+func (l *Lexer) Read16BE() uint16 {
+	v := l.consume(2)
+	if v == nil {
+		return 0
+	}
+	return binary.BigEndian.Uint16(v)
+}
+
+// Read32LE reads a 32-bit little endian value, regardless of the Lexer's
+// byte order, which is left unchanged.
+//
+// If an error occurred, Error() will return a non-nil error.
+func (l *Lexer) Read32LE() uint32 {
+	v := l.consume(4)
+	if v == nil {
+		return 0
+	}
+	return binary.LittleEndian.Uint32(v)
+}
+
+// Read32BE reads a 32-bit big endian value, regardless of the Lexer's
+// byte order, which is left unchanged.
+//
+// If an error occurred, Error() will return a non-nil error.
+func (l *Lexer) Read32BE() uint32 {
+	v := l.consume(4)
+	if v == nil {
+		return 0
+	}
+	return binary.BigEndian.Uint32(v)
+}
+
+// Read64LE reads a 64-bit little endian value, regardless of the Lexer's
+// byte order, which is left unchanged.
+//
+// If an error occurred, Error() will return a non-nil error.
+func (l *Lexer) Read64LE() uint64 {
+	v := l.consume(8)
+	if v == nil {
+		return 0
+	}
+	return binary.LittleEndian.Uint64(v)
+}
+
+// Read64BE reads a 64-bit big endian value, regardless of the Lexer's
+// byte order, which is left unchanged.
+//
+// If an error occurred, Error() will return a non-nil error.
+func (l *Lexer) Read64BE() uint64 {
+	v := l.consume(8)
+	if v == nil {
+		return 0
+	}
+	return binary.BigEndian.Uint64(v)
+}
+
+// Write16LE writes v as a 16-bit little endian value, regardless of the
+// Lexer's byte order, which is left unchanged.
+//
+// If an error occurred, Error() will return a non-nil error.
+func (l *Lexer) Write16LE(v uint16) {
+	if b := l.append(2); b != nil {
+		binary.LittleEndian.PutUint16(b, v)
+	}
+}
+
+// Write16BE writes v as a 16-bit big endian value, regardless of the
+// Lexer's byte order, which is left unchanged.
+//
+// If an error occurred, Error() will return a non-nil error.
+func (l *Lexer) Write16BE(v uint16) {
+	if b := l.append(2); b != nil {
+		binary.BigEndian.PutUint16(b, v)
+	}
+}
+
+// Write32LE writes v as a 32-bit little endian value, regardless of the
+// Lexer's byte order, which is left unchanged.
+//
+// If an error occurred, Error() will return a non-nil error.
+func (l *Lexer) Write32LE(v uint32) {
+	if b := l.append(4); b != nil {
+		binary.LittleEndian.PutUint32(b, v)
+	}
+}
+
+// Write32BE writes v as a 32-bit big endian value, regardless of the
+// Lexer's byte order, which is left unchanged.
+//
+// If an error occurred, Error() will return a non-nil error.
+func (l *Lexer) Write32BE(v uint32) {
+	if b := l.append(4); b != nil {
+		binary.BigEndian.PutUint32(b, v)
+	}
+}
+
+// Write64LE writes v as a 64-bit little endian value, regardless of the
+// Lexer's byte order, which is left unchanged.
+//
+// If an error occurred, Error() will return a non-nil error.
+func (l *Lexer) Write64LE(v uint64) {
+	if b := l.append(8); b != nil {
+		binary.LittleEndian.PutUint64(b, v)
+	}
+}
+
+// Write64BE writes v as a 64-bit big endian value, regardless of the
+// Lexer's byte order, which is left unchanged.
+//
+// If an error occurred, Error() will return a non-nil error.
+func (l *Lexer) Write64BE(v uint64) {
+	if b := l.append(8); b != nil {
+		binary.BigEndian.PutUint64(b, v)
+	}
+}
+
+// DetectOrderByMagic infers a byte order from the 4-byte magic number at the
+// start of b. It returns binary.BigEndian if the magic read big endian is
+// wantBE, binary.LittleEndian if read little endian it is wantLE, and nil,
+// false if neither matches or b is too short.
+//
+// Formats with a single magic pass it as both, e.g. for pcap files, whose
+// writers store 0xa1b2c3d4 in their native order:
+//
+//	order, ok := DetectOrderByMagic(b, 0xa1b2c3d4, 0xa1b2c3d4)
+func DetectOrderByMagic(b []byte, wantBE, wantLE uint32) (binary.ByteOrder, bool) {
+	if len(b) < 4 {
+		return nil, false
+	}
+	if binary.BigEndian.Uint32(b) == wantBE {
+		return binary.BigEndian, true
+	}
+	if binary.LittleEndian.Uint32(b) == wantLE {
+		return binary.LittleEndian, true
+	}
+	return nil, false
+}
+
+// DetectByMagic peeks the 4-byte magic number at the read position and, if
+// DetectOrderByMagic matches it with wantBE or wantLE, sets the Lexer's byte
+// order accordingly. Nothing is consumed, and no error is set.
+//
+// It returns false, leaving the byte order unchanged, if neither matches.
+func (l *Lexer) DetectByMagic(wantBE, wantLE uint32) bool {
+	l.Has(4)
+	order, ok := DetectOrderByMagic(l.Data(), wantBE, wantLE)
+	if ok {
+		l.order = order
+	}
+	return ok
+}
@@ -0,0 +1,101 @@
+// Copyright 2018 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package uio
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"reflect"
+	"testing"
+)
+
+func TestExplicitEndian(t *testing.T) {
+	for _, order := range []binary.ByteOrder{binary.LittleEndian, binary.BigEndian} {
+		l := NewLexer(NewBuffer(nil), order)
+		l.Write16LE(0x0102)
+		l.Write16BE(0x0102)
+		l.Write32LE(0x01020304)
+		l.Write32BE(0x01020304)
+		l.Write64LE(0x0102030405060708)
+		l.Write64BE(0x0102030405060708)
+		want := []byte{
+			0x02, 0x01,
+			0x01, 0x02,
+			0x04, 0x03, 0x02, 0x01,
+			0x01, 0x02, 0x03, 0x04,
+			0x08, 0x07, 0x06, 0x05, 0x04, 0x03, 0x02, 0x01,
+			0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08,
+		}
+		if got := l.Data(); !reflect.DeepEqual(got, want) {
+			t.Errorf("%v: Data() = %#v, want %#v", order, got, want)
+		}
+
+		if got := l.Read16LE(); got != 0x0102 {
+			t.Errorf("%v: Read16LE() = %#x, want 0x102", order, got)
+		}
+		if got := l.Read16BE(); got != 0x0102 {
+			t.Errorf("%v: Read16BE() = %#x, want 0x102", order, got)
+		}
+		if got := l.Read32LE(); got != 0x01020304 {
+			t.Errorf("%v: Read32LE() = %#x, want 0x1020304", order, got)
+		}
+		if got := l.Read32BE(); got != 0x01020304 {
+			t.Errorf("%v: Read32BE() = %#x, want 0x1020304", order, got)
+		}
+		if got := l.Read64LE(); got != 0x0102030405060708 {
+			t.Errorf("%v: Read64LE() = %#x, want 0x102030405060708", order, got)
+		}
+		if got := l.Read64BE(); got != 0x0102030405060708 {
+			t.Errorf("%v: Read64BE() = %#x, want 0x102030405060708", order, got)
+		}
+		if l.Order() != order {
+			t.Errorf("Order() = %v, want %v", l.Order(), order)
+		}
+		if err := l.Error(); err != nil {
+			t.Errorf("%v: Error() = %v, want nil", order, err)
+		}
+		if got := l.Read32BE(); got != 0 {
+			t.Errorf("%v: Read32BE() past the end = %#x, want 0", order, got)
+		}
+		if err := l.Error(); !errors.Is(err, io.ErrUnexpectedEOF) {
+			t.Errorf("%v: Error() = %v, want %v", order, err, io.ErrUnexpectedEOF)
+		}
+	}
+}
+
+func TestDetectOrderByMagic(t *testing.T) {
+	for i, tt := range []struct {
+		in     []byte
+		be, le uint32
+		want   binary.ByteOrder
+	}{
+		{in: []byte{0xa1, 0xb2, 0xc3, 0xd4}, be: 0xa1b2c3d4, le: 0xa1b2c3d4, want: binary.BigEndian},
+		{in: []byte{0xd4, 0xc3, 0xb2, 0xa1, 0x02}, be: 0xa1b2c3d4, le: 0xa1b2c3d4, want: binary.LittleEndian},
+		{in: []byte{0xfe, 0xed, 0xfa, 0xce}, be: 0xfeedface, le: 0xcefaedfe, want: binary.BigEndian},
+		{in: []byte{0xfe, 0xed, 0xfa, 0xce}, be: 0xcefaedfe, le: 0xcefaedfe, want: binary.LittleEndian},
+		{in: []byte{0xa1, 0xb2, 0xc3, 0xd5}, be: 0xa1b2c3d4, le: 0xa1b2c3d4},
+		{in: []byte{0xa1, 0xb2, 0xc3}, be: 0xa1b2c3d4, le: 0xa1b2c3d4},
+	} {
+		order, ok := DetectOrderByMagic(tt.in, tt.be, tt.le)
+		if order != tt.want || ok != (tt.want != nil) {
+			t.Errorf("#%d: DetectOrderByMagic() = %v, %t, want %v, %t", i, order, ok, tt.want, tt.want != nil)
+		}
+
+		l := NewLexer(NewBuffer(tt.in), nil)
+		if ok := l.DetectByMagic(tt.be, tt.le); ok != (tt.want != nil) {
+			t.Errorf("#%d: DetectByMagic() = %t, want %t", i, ok, tt.want != nil)
+		}
+		if l.Order() != tt.want {
+			t.Errorf("#%d: Order() = %v, want %v", i, l.Order(), tt.want)
+		}
+		if l.Len() != len(tt.in) {
+			t.Errorf("#%d: Len() = %d, want %d", i, l.Len(), len(tt.in))
+		}
+		if err := l.Error(); err != nil {
+			t.Errorf("#%d: Error() = %v, want nil", i, err)
+		}
+	}
+}
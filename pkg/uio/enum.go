@@ -0,0 +1,94 @@
+// Copyright 2018 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package uio
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+)
+
+// ErrInvalidValue is set when a value read is not one of the values
+// allowed for its field.
+var ErrInvalidValue = errors.New("invalid value")
+
+// enumError records that v, read at the current position, is not valid. The
+// caller consumes v afterwards, so that the error's offset is that of v.
+func (l *Lexer) enumError(v uint64) {
+	l.setError(fmt.Errorf("%w %#x", ErrInvalidValue, v))
+}
+
+// ReadEnum8 reads an 8-bit value that must be one of valid, e.g. a message
+// type.
+//
+// If the value is not in valid, it is still consumed and returned, and
+// Error() will return an error wrapping ErrInvalidValue that includes the
+// value and its offset.
+func (l *Lexer) ReadEnum8(valid ...uint8) uint8 {
+	if l.Has(1) {
+		v := l.Data()[0]
+		for _, w := range valid {
+			if v == w {
+				return l.Read8()
+			}
+		}
+		l.enumError(uint64(v))
+	}
+	return l.Read8()
+}
+
+// ReadEnum16 reads a 16-bit value that must be one of valid. See ReadEnum8.
+func (l *Lexer) ReadEnum16(valid ...uint16) uint16 {
+	if l.Has(2) {
+		v := l.order.Uint16(l.Data())
+		for _, w := range valid {
+			if v == w {
+				return l.Read16()
+			}
+		}
+		l.enumError(uint64(v))
+	}
+	return l.Read16()
+}
+
+// ReadEnum32 reads a 32-bit value that must be one of valid. See ReadEnum8.
+func (l *Lexer) ReadEnum32(valid ...uint32) uint32 {
+	if l.Has(4) {
+		v := l.order.Uint32(l.Data())
+		for _, w := range valid {
+			if v == w {
+				return l.Read32()
+			}
+		}
+		l.enumError(uint64(v))
+	}
+	return l.Read32()
+}
+
+// PeekExpect returns true if the next bytes are magic, without consuming
+// them.
+func (l *Lexer) PeekExpect(magic []byte) bool {
+	return l.Has(len(magic)) && bytes.HasPrefix(l.Data(), magic)
+}
+
+// Expect consumes len(magic) bytes and returns true if they are magic, e.g.
+// to check the "\x7fELF" at the start of an ELF file:
+//
+//	if !l.Expect([]byte("\x7fELF")) {
+//		return l.Error()
+//	}
+//
+// If they are not, they are still consumed, and Error() will return an error
+// wrapping ErrInvalidValue that includes the bytes read and their offset.
+func (l *Lexer) Expect(magic []byte) bool {
+	if l.Has(len(magic)) && !l.PeekExpect(magic) {
+		l.setError(fmt.Errorf("%w %q, want %q", ErrInvalidValue, l.Data()[:len(magic)], magic))
+		l.consume(len(magic))
+		return false
+	}
+	errs := len(l.errs)
+	l.consume(len(magic))
+	return len(l.errs) == errs
+}
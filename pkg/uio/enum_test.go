@@ -0,0 +1,92 @@
+// Copyright 2018 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package uio
+
+import (
+	"errors"
+	"io"
+	"testing"
+)
+
+func TestReadEnum(t *testing.T) {
+	l := NewLittleEndianBuffer([]byte{0x01, 0x02, 0x00, 0x03, 0x00, 0x00, 0x00})
+	if got := l.ReadEnum8(1, 2, 3); got != 1 {
+		t.Errorf("ReadEnum8() = %d, want 1", got)
+	}
+	if got := l.ReadEnum16(0x0102, 0x0002); got != 0x0002 {
+		t.Errorf("ReadEnum16() = %#x, want 0x2", got)
+	}
+	if got := l.ReadEnum32(3); got != 3 {
+		t.Errorf("ReadEnum32() = %d, want 3", got)
+	}
+	if err := l.Error(); err != nil {
+		t.Errorf("Error() = %v, want nil", err)
+	}
+	if got := l.ReadEnum8(1); got != 0 {
+		t.Errorf("ReadEnum8() past the end = %d, want 0", got)
+	}
+	if errs := l.Errors(); len(errs) != 1 || !errors.Is(errs[0], io.ErrUnexpectedEOF) {
+		t.Errorf("Errors() = %v, want one %v", errs, io.ErrUnexpectedEOF)
+	}
+}
+
+func TestReadEnumInvalid(t *testing.T) {
+	l := NewBigEndianBuffer([]byte{0xff, 0x07, 0x00, 0x09})
+	l.Read8()
+	if got := l.Field("opcode").ReadEnum8(1, 2); got != 7 {
+		t.Errorf("ReadEnum8() = %d, want 7", got)
+	}
+	err := l.Error()
+	if !errors.Is(err, ErrInvalidValue) {
+		t.Fatalf("Error() = %v, want %v", err, ErrInvalidValue)
+	}
+	if got, want := err.Error(), `error in field "opcode" at offset 1: invalid value 0x7`; got != want {
+		t.Errorf("Error() = %q, want %q", got, want)
+	}
+	if l.Tell() != 2 {
+		t.Errorf("Tell() = %d, want 2", l.Tell())
+	}
+
+	if got := l.ReadEnum16(); got != 9 {
+		t.Errorf("ReadEnum16() = %d, want 9", got)
+	}
+	if errs := l.Errors(); len(errs) != 2 || !errors.Is(errs[1], ErrInvalidValue) {
+		t.Errorf("Errors() = %v, want two %v", errs, ErrInvalidValue)
+	}
+}
+
+func TestExpect(t *testing.T) {
+	l := NewBigEndianBuffer([]byte("\x7fELF\x02\x7fELF"))
+	if !l.PeekExpect([]byte("\x7fELF")) || l.Tell() != 0 {
+		t.Errorf("PeekExpect() = false or consumed %d bytes, want true and 0", l.Tell())
+	}
+	if !l.Expect([]byte("\x7fELF")) {
+		t.Errorf("Expect() = false, want true")
+	}
+	if l.PeekExpect([]byte("\x7fELF")) {
+		t.Errorf("PeekExpect() = true, want false")
+	}
+	if err := l.Error(); err != nil {
+		t.Errorf("Error() = %v, want nil", err)
+	}
+
+	if l.Expect([]byte("\x01\x7fEL")) {
+		t.Errorf("Expect() with the wrong bytes = true, want false")
+	}
+	var ferr *FieldError
+	if err := l.Error(); !errors.Is(err, ErrInvalidValue) || !errors.As(err, &ferr) || ferr.Offset != 4 {
+		t.Errorf("Expect() with the wrong bytes: Error() = %v, want %v at offset 4", err, ErrInvalidValue)
+	}
+	if got, want := l.Tell(), 8; got != want {
+		t.Errorf("Tell() after Expect() = %d, want %d", got, want)
+	}
+
+	if l.Expect([]byte("F\x00")) {
+		t.Errorf("Expect() past the end = true, want false")
+	}
+	if errs := l.Errors(); len(errs) != 2 || !errors.Is(errs[1], io.ErrUnexpectedEOF) {
+		t.Errorf("Expect() past the end: Errors() = %v, want %v", errs, io.ErrUnexpectedEOF)
+	}
+}
@@ -0,0 +1,64 @@
+// Copyright 2018 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package uio
+
+import (
+	"math"
+)
+
+// fixedToInt converts v to a signed fixed-point number with frac fractional
+// bits, rounding to the nearest representable value and halfway cases away
+// from zero. It returns false if the result is outside [min, max].
+func fixedToInt(v float64, frac uint, min, max int64) (int64, bool) {
+	r := math.Round(math.Ldexp(v, int(frac)))
+	if math.IsNaN(r) || r < float64(min) || r > float64(max) {
+		return 0, false
+	}
+	return int64(r), true
+}
+
+// ReadFixed16_16 reads a signed Q16.16 fixed-point number: a 32-bit two's
+// complement integer in the Lexer's byte order, scaled by 2^-16.
+//
+// If an error occurred, Error() will return a non-nil error.
+func (l *Lexer) ReadFixed16_16() float64 {
+	return math.Ldexp(float64(int32(l.Read32())), -16)
+}
+
+// WriteFixed16_16 writes v as a signed Q16.16 fixed-point number, rounded to
+// the nearest multiple of 2^-16 with halfway cases rounded away from zero.
+//
+// If v is outside the representable range, [-32768, 32768 - 2^-16], or NaN,
+// nothing is written and Error() will return ErrOverflow.
+func (l *Lexer) WriteFixed16_16(v float64) {
+	n, ok := fixedToInt(v, 16, math.MinInt32, math.MaxInt32)
+	if !ok {
+		l.setError(ErrOverflow)
+		return
+	}
+	l.Write32(uint32(n))
+}
+
+// ReadFixed8_8 reads a signed Q8.8 fixed-point number: a 16-bit two's
+// complement integer in the Lexer's byte order, scaled by 2^-8.
+//
+// If an error occurred, Error() will return a non-nil error.
+func (l *Lexer) ReadFixed8_8() float64 {
+	return math.Ldexp(float64(int16(l.Read16())), -8)
+}
+
+// WriteFixed8_8 writes v as a signed Q8.8 fixed-point number, rounded to the
+// nearest multiple of 2^-8 with halfway cases rounded away from zero.
+//
+// If v is outside the representable range, [-128, 128 - 2^-8], or NaN,
+// nothing is written and Error() will return ErrOverflow.
+func (l *Lexer) WriteFixed8_8(v float64) {
+	n, ok := fixedToInt(v, 8, math.MinInt16, math.MaxInt16)
+	if !ok {
+		l.setError(ErrOverflow)
+		return
+	}
+	l.Write16(uint16(n))
+}
@@ -0,0 +1,72 @@
+// Copyright 2018 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package uio
+
+import (
+	"errors"
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestFixed16_16(t *testing.T) {
+	for i, tt := range []struct {
+		in   float64
+		want []byte
+		out  float64
+	}{
+		{in: 1, want: []byte{0x00, 0x01, 0x00, 0x00}, out: 1},
+		{in: -1.5, want: []byte{0xff, 0xfe, 0x80, 0x00}, out: -1.5},
+		{in: 72.25, want: []byte{0x00, 0x48, 0x40, 0x00}, out: 72.25},
+		// Rounds to the nearest multiple of 2^-16.
+		{in: 0.1, want: []byte{0x00, 0x00, 0x19, 0x9a}, out: 6554.0 / 65536},
+		{in: -32768, want: []byte{0x80, 0x00, 0x00, 0x00}, out: -32768},
+	} {
+		l := NewBigEndianBuffer(nil)
+		l.WriteFixed16_16(tt.in)
+		if got := l.Data(); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("#%d: WriteFixed16_16(%v) = %#v, want %#v", i, tt.in, got, tt.want)
+		}
+		if got := l.ReadFixed16_16(); got != tt.out {
+			t.Errorf("#%d: ReadFixed16_16() = %v, want %v", i, got, tt.out)
+		}
+		if err := l.Error(); err != nil {
+			t.Errorf("#%d: Error() = %v, want nil", i, err)
+		}
+	}
+}
+
+func TestFixed8_8(t *testing.T) {
+	l := NewLittleEndianBuffer(nil)
+	l.WriteFixed8_8(1.5)
+	l.WriteFixed8_8(-0.00390625)
+	if got, want := l.Data(), []byte{0x80, 0x01, 0xff, 0xff}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Data() = %#v, want %#v", got, want)
+	}
+	for _, want := range []float64{1.5, -0.00390625} {
+		if got := l.ReadFixed8_8(); got != want {
+			t.Errorf("ReadFixed8_8() = %v, want %v", got, want)
+		}
+	}
+}
+
+func TestFixedOverflow(t *testing.T) {
+	l := NewLittleEndianBuffer(nil)
+	l.WriteFixed16_16(32768)
+	l.WriteFixed16_16(math.NaN())
+	l.WriteFixed8_8(-128.01)
+	l.WriteFixed8_8(math.Inf(1))
+	if got := l.Errors(); len(got) != 4 {
+		t.Errorf("Errors() = %v, want 4 errors", got)
+	}
+	for _, err := range l.Errors() {
+		if !errors.Is(err, ErrOverflow) {
+			t.Errorf("error = %v, want %v", err, ErrOverflow)
+		}
+	}
+	if l.Len() != 0 {
+		t.Errorf("Len() = %d, want 0", l.Len())
+	}
+}
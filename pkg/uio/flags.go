@@ -0,0 +1,109 @@
+// Copyright 2018 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package uio
+
+// Flags is a flags word or bitmask, as read by ReadFlags8, ReadFlags16 and
+// ReadFlags32. Bit 0 is the least significant bit.
+//
+// Its methods make decoding and building flags self-documenting:
+//
+//	f := l.ReadFlags8()
+//	more := f.Has(0)
+//	version := f.Field(4, 3)
+//
+//	l.WriteFlags8(uio.Flags(0).Set(0).SetField(4, 3, version))
+type Flags uint64
+
+// lowMask returns a mask of the low width bits.
+func lowMask(width uint) uint64 {
+	if width >= 64 {
+		return ^uint64(0)
+	}
+	return 1<<width - 1
+}
+
+// Has returns true if bit is set.
+func (f Flags) Has(bit uint) bool {
+	return bit < 64 && f&(1<<bit) != 0
+}
+
+// Field returns the width-bit field starting at bit shift.
+func (f Flags) Field(shift, width uint) uint64 {
+	if shift >= 64 {
+		return 0
+	}
+	return uint64(f) >> shift & lowMask(width)
+}
+
+// Set returns f with bit set.
+func (f Flags) Set(bit uint) Flags {
+	if bit >= 64 {
+		return f
+	}
+	return f | 1<<bit
+}
+
+// Clear returns f with bit cleared.
+func (f Flags) Clear(bit uint) Flags {
+	if bit >= 64 {
+		return f
+	}
+	return f &^ (1 << bit)
+}
+
+// SetField returns f with the width-bit field starting at bit shift set to
+// the low width bits of v.
+func (f Flags) SetField(shift, width uint, v uint64) Flags {
+	if shift >= 64 {
+		return f
+	}
+	m := lowMask(width) << shift
+	return Flags(uint64(f)&^m | v<<shift&m)
+}
+
+// ReadFlags8 reads an 8-bit flags word.
+//
+// If an error occurred, Error() will return a non-nil error.
+func (l *Lexer) ReadFlags8() Flags {
+	return Flags(l.Read8())
+}
+
+// ReadFlags16 reads a 16-bit flags word in the Lexer's byte order.
+//
+// If an error occurred, Error() will return a non-nil error.
+func (l *Lexer) ReadFlags16() Flags {
+	return Flags(l.Read16())
+}
+
+// ReadFlags32 reads a 32-bit flags word in the Lexer's byte order.
+//
+// If an error occurred, Error() will return a non-nil error.
+func (l *Lexer) ReadFlags32() Flags {
+	return Flags(l.Read32())
+}
+
+// WriteFlags8 writes f as an 8-bit flags word.
+//
+// If f has bits set above bit 7, nothing is written and Error() will return
+// ErrOverflow.
+func (l *Lexer) WriteFlags8(f Flags) {
+	l.WriteUintN(1, uint64(f))
+}
+
+// WriteFlags16 writes f as a 16-bit flags word in the Lexer's byte order.
+//
+// If f has bits set above bit 15, nothing is written and Error() will return
+// ErrOverflow.
+func (l *Lexer) WriteFlags16(f Flags) {
+	l.WriteUintN(2, uint64(f))
+}
+
+// WriteFlags32 writes f as a 32-bit flags word in the Lexer's byte order.
+//
+// If f has bits set above bit 31, nothing is written and Error() will return
+// ErrOverflow.
+func (l *Lexer) WriteFlags32(f Flags) {
+	l.WriteUintN(4, uint64(f))
+}
@@ -0,0 +1,75 @@
+// Copyright 2018 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package uio
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestFlags(t *testing.T) {
+	f := Flags(0xb4) // 1011 0100
+	for bit, want := range []bool{false, false, true, false, true, true, false, true, false} {
+		if got := f.Has(uint(bit)); got != want {
+			t.Errorf("%#x.Has(%d) = %v, want %v", uint64(f), bit, got, want)
+		}
+	}
+	if f.Has(64) {
+		t.Errorf("%#x.Has(64) = true, want false", uint64(f))
+	}
+
+	for i, tt := range []struct {
+		shift, width uint
+		want         uint64
+	}{
+		{shift: 0, width: 4, want: 0x4},
+		{shift: 4, width: 4, want: 0xb},
+		{shift: 2, width: 3, want: 0x5},
+		{shift: 0, width: 64, want: 0xb4},
+		{shift: 64, width: 1, want: 0},
+	} {
+		if got := f.Field(tt.shift, tt.width); got != tt.want {
+			t.Errorf("#%d: Field(%d, %d) = %#x, want %#x", i, tt.shift, tt.width, got, tt.want)
+		}
+	}
+
+	if got, want := Flags(0).Set(0).Set(7).Clear(0).Set(2), Flags(0x84); got != want {
+		t.Errorf("Set/Clear = %#x, want %#x", uint64(got), uint64(want))
+	}
+	if got, want := f.SetField(4, 4, 0x1f), Flags(0xf4); got != want {
+		t.Errorf("SetField(4, 4, 0x1f) = %#x, want %#x", uint64(got), uint64(want))
+	}
+	if got, want := f.SetField(2, 3, 0), Flags(0xa0); got != want {
+		t.Errorf("SetField(2, 3, 0) = %#x, want %#x", uint64(got), uint64(want))
+	}
+}
+
+func TestReadWriteFlags(t *testing.T) {
+	l := NewLittleEndianBuffer(nil)
+	l.WriteFlags8(Flags(0).Set(0).SetField(4, 3, 5))
+	l.WriteFlags16(Flags(0).Set(15))
+	l.WriteFlags32(Flags(0).Set(31).Set(1))
+	if got, want := l.Data(), []byte{0x51, 0x00, 0x80, 0x02, 0x00, 0x00, 0x80}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Data() = %#v, want %#v", got, want)
+	}
+	if got := l.ReadFlags8(); !got.Has(0) || got.Field(4, 3) != 5 {
+		t.Errorf("ReadFlags8() = %#x, want bit 0 and field 5 at bit 4", uint64(got))
+	}
+	if got := l.ReadFlags16(); got != 0x8000 {
+		t.Errorf("ReadFlags16() = %#x, want 0x8000", uint64(got))
+	}
+	if got := l.ReadFlags32(); got != 0x80000002 {
+		t.Errorf("ReadFlags32() = %#x, want 0x80000002", uint64(got))
+	}
+	if err := l.Error(); err != nil {
+		t.Errorf("Error() = %v, want nil", err)
+	}
+
+	l.WriteFlags8(Flags(0).Set(8))
+	if err := l.Error(); !errors.Is(err, ErrOverflow) || l.Len() != 0 {
+		t.Errorf("WriteFlags8(bit 8): Error() = %v, Len() = %d, want %v, 0", err, l.Len(), ErrOverflow)
+	}
+}
@@ -0,0 +1,111 @@
+// Copyright 2018 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package uio
+
+import (
+	"errors"
+	"io"
+)
+
+// ErrInvalidEscape is set when an escape byte in a framed sequence is
+// followed by a byte it cannot escape.
+var ErrInvalidEscape = errors.New("invalid escape sequence")
+
+// ReadFramed consumes a frame terminated by end, as used by SLIP and
+// PPP-style framing over serial links, and returns a copy of it with escape
+// sequences decoded: esc followed by escEnd stands for end, and esc followed
+// by escEsc for esc. For SLIP (RFC 1055):
+//
+//	frame := l.ReadFramed(0xc0, 0xdb, 0xdc, 0xdd)
+//
+// Leading end bytes, which SLIP senders use to flush line noise, yield
+// empty frames.
+//
+// If esc is followed by any other byte, that byte is kept as is, the rest
+// of the frame is still consumed, and Error() will return ErrInvalidEscape;
+// likewise if esc comes right before end.
+// If the Buffer ends before end, all remaining bytes are consumed and
+// decoded, and Error() will return io.ErrUnexpectedEOF.
+func (l *Lexer) ReadFramed(end, esc, escEnd, escEsc byte) []byte {
+	n := l.indexByte(end, maxInt)
+	terminated := n >= 0
+	if !terminated {
+		n = l.Len()
+	}
+	if !l.checkAlloc(n) {
+		return nil
+	}
+	raw := l.consume(n)
+	if terminated {
+		l.consume(1)
+	}
+
+	frame := make([]byte, 0, len(raw))
+	var err error
+	for i := 0; i < len(raw); i++ {
+		c := raw[i]
+		if c != esc {
+			frame = append(frame, c)
+			continue
+		}
+		if i++; i == len(raw) {
+			if terminated {
+				err = ErrInvalidEscape
+			} else {
+				err = io.ErrUnexpectedEOF
+			}
+			break
+		}
+		switch raw[i] {
+		case escEnd:
+			frame = append(frame, end)
+		case escEsc:
+			frame = append(frame, esc)
+		default:
+			if err == nil {
+				err = ErrInvalidEscape
+			}
+			frame = append(frame, raw[i])
+		}
+	}
+	if err == nil && !terminated {
+		err = io.ErrUnexpectedEOF
+	}
+	l.setError(err)
+	return frame
+}
+
+// WriteFramed writes p as a frame read by ReadFramed: each end in p is
+// replaced by esc followed by escEnd, each esc by esc followed by escEsc,
+// and the frame is terminated by end.
+//
+// If an error occurred, Error() will return a non-nil error.
+func (l *Lexer) WriteFramed(p []byte, end, esc, escEnd, escEsc byte) {
+	n := len(p) + 1
+	for _, c := range p {
+		if c == end || c == esc {
+			n++
+		}
+	}
+	b := l.append(n)
+	if b == nil {
+		return
+	}
+	i := 0
+	for _, c := range p {
+		switch c {
+		case end:
+			b[i], b[i+1] = esc, escEnd
+			i += 2
+		case esc:
+			b[i], b[i+1] = esc, escEsc
+			i += 2
+		default:
+			b[i] = c
+			i++
+		}
+	}
+	b[i] = end
+}
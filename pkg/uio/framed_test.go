@@ -0,0 +1,87 @@
+// Copyright 2018 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package uio
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"reflect"
+	"testing"
+	"testing/iotest"
+)
+
+const (
+	slipEnd    = 0xc0
+	slipEsc    = 0xdb
+	slipEscEnd = 0xdc
+	slipEscEsc = 0xdd
+)
+
+func TestFramed(t *testing.T) {
+	for i, tt := range []struct {
+		frame []byte
+		want  []byte
+	}{
+		{frame: []byte{}, want: []byte{slipEnd}},
+		{frame: []byte("hi"), want: []byte{'h', 'i', slipEnd}},
+		{frame: []byte{0x01, slipEnd, 0x02, slipEsc}, want: []byte{0x01, slipEsc, slipEscEnd, 0x02, slipEsc, slipEscEsc, slipEnd}},
+		{frame: []byte{slipEscEnd, slipEscEsc}, want: []byte{slipEscEnd, slipEscEsc, slipEnd}},
+	} {
+		l := NewBigEndianBuffer(nil)
+		l.WriteFramed(tt.frame, slipEnd, slipEsc, slipEscEnd, slipEscEsc)
+		if got := l.Data(); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("#%d: WriteFramed() = %#v, want %#v", i, got, tt.want)
+		}
+		if got := l.ReadFramed(slipEnd, slipEsc, slipEscEnd, slipEscEsc); !reflect.DeepEqual(got, tt.frame) {
+			t.Errorf("#%d: ReadFramed() = %#v, want %#v", i, got, tt.frame)
+		}
+		if err := l.Error(); err != nil {
+			t.Errorf("#%d: Error() = %v, want nil", i, err)
+		}
+	}
+}
+
+func TestReadFramedStream(t *testing.T) {
+	in := []byte{slipEnd, 'a', slipEsc, slipEscEnd, slipEnd, 'b', slipEnd}
+	l := NewReaderLexer(iotest.OneByteReader(bytes.NewReader(in)), binary.BigEndian)
+	var got [][]byte
+	for !l.Finished() {
+		got = append(got, l.ReadFramed(slipEnd, slipEsc, slipEscEnd, slipEscEsc))
+	}
+	want := [][]byte{{}, {'a', slipEnd}, {'b'}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ReadFramed() = %#v, want %#v", got, want)
+	}
+	if err := l.Error(); err != nil {
+		t.Errorf("Error() = %v, want nil", err)
+	}
+}
+
+func TestReadFramedErrors(t *testing.T) {
+	for i, tt := range []struct {
+		in   []byte
+		want []byte
+		err  error
+		left int
+	}{
+		{in: []byte{'a', 'b'}, want: []byte("ab"), err: io.ErrUnexpectedEOF},
+		{in: []byte{'a', slipEsc}, want: []byte("a"), err: io.ErrUnexpectedEOF},
+		{in: []byte{'a', slipEsc, slipEnd, 'b', slipEnd}, want: []byte("a"), err: ErrInvalidEscape, left: 2},
+		{in: []byte{slipEsc, 'x', 'y', slipEnd, 'z'}, want: []byte("xy"), err: ErrInvalidEscape, left: 1},
+	} {
+		l := NewBigEndianBuffer(tt.in)
+		if got := l.ReadFramed(slipEnd, slipEsc, slipEscEnd, slipEscEsc); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("#%d: ReadFramed() = %#v, want %#v", i, got, tt.want)
+		}
+		if err := l.Error(); !errors.Is(err, tt.err) {
+			t.Errorf("#%d: Error() = %v, want %v", i, err, tt.err)
+		}
+		if l.Len() != tt.left {
+			t.Errorf("#%d: Len() = %d, want %d", i, l.Len(), tt.left)
+		}
+	}
+}
@@ -0,0 +1,75 @@
+// Copyright 2018 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package uio
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// ErrRoundTrip is returned by RoundTrip when remarshaling an unmarshaled
+// value does not reproduce the original bytes.
+var ErrRoundTrip = errors.New("round trip mismatch")
+
+// FuzzUnmarshal unmarshals data into u in the given byte order, the way a
+// fuzz target should: no read may allocate more than len(data) bytes, and an
+// error set in the Lexer is returned even if Unmarshal itself returned nil.
+//
+// A native Go fuzz target for a type T built on it looks like:
+//
+//	func FuzzT(f *testing.F) {
+//		f.Add(seed)
+//		f.Fuzz(func(t *testing.T, data []byte) {
+//			var v T
+//			if err := uio.FuzzUnmarshal(data, binary.BigEndian, &v); err != nil {
+//				return
+//			}
+//			if err := uio.RoundTrip(binary.BigEndian, &v, new(T)); err != nil {
+//				t.Fatal(err)
+//			}
+//		})
+//	}
+//
+// The fuzzer itself reports any panic in Unmarshal or Marshal.
+func FuzzUnmarshal(data []byte, order binary.ByteOrder, u Unmarshaler) error {
+	l := NewLexer(NewBuffer(data), order)
+	l.SetMaxAlloc(len(data))
+	return UnmarshalAll(l, u)
+}
+
+// RoundTrip marshals m, unmarshals the result into u and checks that all of
+// it was consumed. If u is also a Marshaler, it then checks that marshaling
+// u reproduces the same bytes, and returns an error wrapping ErrRoundTrip if
+// not.
+func RoundTrip(order binary.ByteOrder, m Marshaler, u Unmarshaler) error {
+	l := NewLexer(NewBuffer(nil), order)
+	m.Marshal(l)
+	if err := l.Error(); err != nil {
+		return err
+	}
+	want := l.Data()
+	if err := UnmarshalAll(l, u); err != nil {
+		return err
+	}
+	l.AssertFinished()
+	if err := l.Error(); err != nil {
+		return err
+	}
+
+	um, ok := u.(Marshaler)
+	if !ok {
+		return nil
+	}
+	l = NewLexer(NewBuffer(nil), order)
+	um.Marshal(l)
+	if err := l.Error(); err != nil {
+		return err
+	}
+	if off, equal := l.Diff(want); !equal {
+		return fmt.Errorf("%w at offset %d", ErrRoundTrip, off)
+	}
+	return nil
+}
@@ -0,0 +1,27 @@
+// Copyright 2018 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build go1.18
+// +build go1.18
+
+package uio
+
+import (
+	"encoding/binary"
+	"testing"
+)
+
+func FuzzName(f *testing.F) {
+	f.Add([]byte{0x03, 'f', 'o', 'o'})
+	f.Add([]byte{0xff})
+	f.Fuzz(func(t *testing.T, data []byte) {
+		var n name
+		if err := FuzzUnmarshal(data, binary.BigEndian, &n); err != nil {
+			return
+		}
+		if err := RoundTrip(binary.BigEndian, &n, new(name)); err != nil {
+			t.Fatal(err)
+		}
+	})
+}
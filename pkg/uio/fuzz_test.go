@@ -0,0 +1,59 @@
+// Copyright 2018 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package uio
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"testing"
+)
+
+// swapped unmarshals its fields in the wrong order.
+type swapped struct {
+	A, B uint8
+}
+
+func (s *swapped) Marshal(l *Lexer) {
+	l.Write8(s.A)
+	l.Write8(s.B)
+}
+
+func (s *swapped) Unmarshal(l *Lexer) error {
+	s.B = l.Read8()
+	s.A = l.Read8()
+	return nil
+}
+
+func TestFuzzUnmarshal(t *testing.T) {
+	var h header
+	if err := FuzzUnmarshal([]byte{0x01, 0x02, 0x03}, binary.BigEndian, &h); err != nil || h != (header{Type: 1, Len: 0x0203}) {
+		t.Errorf("FuzzUnmarshal() = %v, %v, want nil, %v", err, h, header{Type: 1, Len: 0x0203})
+	}
+
+	// name.Unmarshal does not return the Lexer's error itself.
+	var n name
+	if err := FuzzUnmarshal([]byte{0x02, 'a'}, binary.BigEndian, &n); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("FuzzUnmarshal() = %v, want %v", err, io.ErrUnexpectedEOF)
+	}
+
+	// A length longer than the input is rejected before reading it.
+	if err := FuzzUnmarshal([]byte{0xff, 'a'}, binary.BigEndian, &n); !errors.Is(err, ErrTooLong) {
+		t.Errorf("FuzzUnmarshal() = %v, want %v", err, ErrTooLong)
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	if err := RoundTrip(binary.LittleEndian, &header{Type: 1, Len: 0x0203}, new(header)); err != nil {
+		t.Errorf("RoundTrip(header) = %v, want nil", err)
+	}
+	if err := RoundTrip(binary.LittleEndian, &swapped{A: 1, B: 2}, new(swapped)); !errors.Is(err, ErrRoundTrip) {
+		t.Errorf("RoundTrip(swapped) = %v, want %v", err, ErrRoundTrip)
+	}
+	n := name("foo")
+	if err := RoundTrip(binary.LittleEndian, &n, new(header)); !errors.Is(err, ErrUnreadBytes) {
+		t.Errorf("RoundTrip(name, header) = %v, want %v", err, ErrUnreadBytes)
+	}
+}
@@ -0,0 +1,156 @@
+// Copyright 2018 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build go1.18
+// +build go1.18
+
+package uio
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"unsafe"
+)
+
+// Integer is the set of fixed-size integer types that ReadInt and WriteInt
+// support.
+type Integer interface {
+	~int8 | ~int16 | ~int32 | ~int64 | ~uint8 | ~uint16 | ~uint32 | ~uint64
+}
+
+// ReadInt reads an integer of type T from l, using T's size and l's byte
+// order.
+//
+// If an error occurred, l.Error() will return a non-nil error.
+func ReadInt[T Integer](l *Lexer) T {
+	var v T
+	switch unsafe.Sizeof(v) {
+	case 1:
+		return T(l.Read8())
+	case 2:
+		return T(l.Read16())
+	case 4:
+		return T(l.Read32())
+	default:
+		return T(l.Read64())
+	}
+}
+
+// WriteInt writes v to l, using T's size and l's byte order.
+//
+// If an error occurred, l.Error() will return a non-nil error.
+func WriteInt[T Integer](l *Lexer, v T) {
+	switch unsafe.Sizeof(v) {
+	case 1:
+		l.Write8(uint8(v))
+	case 2:
+		l.Write16(uint16(v))
+	case 4:
+		l.Write32(uint32(v))
+	default:
+		l.Write64(uint64(v))
+	}
+}
+
+// ReadSlice reads a countWidth-byte count in l's byte order, followed by
+// that many elements, each created by newT and filled in by its Unmarshal
+// method:
+//
+//	recs := uio.ReadSlice(l, 2, func() *record { return new(record) })
+//
+// ReadSlice stops at the first error returned by Unmarshal or set in l, and
+// returns nil. If the count exceeds the limit set by SetMaxAlloc, nothing
+// after the count is read and l.Error() will return ErrTooLong.
+func ReadSlice[T Unmarshaler](l *Lexer, countWidth int, newT func() T) []T {
+	errs := len(l.errs)
+	n := l.ReadUintN(countWidth)
+	if len(l.errs) > errs {
+		return nil
+	}
+	if !l.checkAlloc64(n) {
+		return nil
+	}
+	if n > uint64(maxInt) {
+		l.setError(ErrTooLong)
+		return nil
+	}
+
+	// Don't trust the count for preallocation: every element takes at
+	// least a byte in any sane format.
+	c := int(n)
+	if c > l.Len() {
+		c = l.Len()
+	}
+	s := make([]T, 0, c)
+	for i := uint64(0); i < n; i++ {
+		v := newT()
+		if err := v.Unmarshal(l); err != nil {
+			if len(l.errs) == errs {
+				l.setError(err)
+			}
+			return nil
+		}
+		if len(l.errs) > errs {
+			return nil
+		}
+		s = append(s, v)
+	}
+	return s
+}
+
+// WriteSlice writes the number of items as a countWidth-byte count in l's
+// byte order, followed by each item's Marshal output.
+//
+// If the count does not fit in countWidth bytes, nothing is written and
+// l.Error() will return ErrOverflow.
+func WriteSlice[T Marshaler](l *Lexer, countWidth int, items []T) {
+	errs := len(l.errs)
+	l.WriteUintN(countWidth, uint64(len(items)))
+	if len(l.errs) > errs {
+		return
+	}
+	for _, v := range items {
+		v.Marshal(l)
+	}
+}
+
+// ReadStructSlice reads count fixed-size values of type T, such as structs
+// of integers and arrays, in l's byte order, using binary.Read:
+//
+//	n := l.Read16()
+//	entries := uio.ReadStructSlice[entry](l, int(n))
+//
+// If T is not a fixed-size type, nothing is read and l.Error() will return
+// ErrUnsupportedType. If fewer bytes than needed are left, nothing is read
+// and l.Error() will return io.ErrUnexpectedEOF. In both cases, and if the
+// values would exceed the limit set by SetMaxAlloc, ReadStructSlice returns
+// nil.
+func ReadStructSlice[T any](l *Lexer, count int) []T {
+	var v T
+	size := binary.Size(v)
+	switch {
+	case size < 0:
+		l.setError(fmt.Errorf("%w: %T", ErrUnsupportedType, v))
+		return nil
+	case count < 0:
+		l.setError(ErrInvalidSize)
+		return nil
+	case !l.checkAllocN(count, size):
+		return nil
+	case size > 0 && count > maxInt/size:
+		l.setError(io.ErrUnexpectedEOF)
+		return nil
+	case !l.Has(count * size):
+		l.setError(io.ErrUnexpectedEOF)
+		return nil
+	}
+	s := make([]T, count)
+	errs := len(l.errs)
+	l.ReadData(s)
+	if len(l.errs) > errs {
+		return nil
+	}
+	return s
+}
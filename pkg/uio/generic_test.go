@@ -0,0 +1,188 @@
+// Copyright 2018 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build go1.18
+// +build go1.18
+
+package uio
+
+import (
+	"errors"
+	"io"
+	"math"
+	"reflect"
+	"testing"
+)
+
+type opcode uint16
+
+func TestReadWriteInt(t *testing.T) {
+	l := NewLittleEndianBuffer(nil)
+	WriteInt(l, uint8(0x01))
+	WriteInt(l, int16(-2))
+	WriteInt(l, opcode(0x0304))
+	WriteInt(l, int32(math.MinInt32))
+	WriteInt(l, uint64(0x05060708090a0b0c))
+
+	want := []byte{
+		0x01,
+		0xfe, 0xff,
+		0x04, 0x03,
+		0x00, 0x00, 0x00, 0x80,
+		0x0c, 0x0b, 0x0a, 0x09, 0x08, 0x07, 0x06, 0x05,
+	}
+	if got := l.Data(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("WriteInt() = %#v, want %#v", got, want)
+	}
+
+	if got := ReadInt[uint8](l); got != 0x01 {
+		t.Errorf("ReadInt[uint8]() = %#x, want 0x01", got)
+	}
+	if got := ReadInt[int16](l); got != -2 {
+		t.Errorf("ReadInt[int16]() = %d, want -2", got)
+	}
+	if got := ReadInt[opcode](l); got != 0x0304 {
+		t.Errorf("ReadInt[opcode]() = %#x, want 0x0304", got)
+	}
+	if got := ReadInt[int32](l); got != math.MinInt32 {
+		t.Errorf("ReadInt[int32]() = %d, want %d", got, math.MinInt32)
+	}
+	if got := ReadInt[uint64](l); got != 0x05060708090a0b0c {
+		t.Errorf("ReadInt[uint64]() = %#x, want 0x05060708090a0b0c", got)
+	}
+	if err := l.Error(); err != nil {
+		t.Errorf("Error() = %v, want nil", err)
+	}
+
+	if got := ReadInt[int64](l); got != 0 {
+		t.Errorf("ReadInt[int64]() on empty buffer = %d, want 0", got)
+	}
+	if err := l.Error(); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("Error() = %v, want %v", err, io.ErrUnexpectedEOF)
+	}
+}
+
+func TestReadWriteSlice(t *testing.T) {
+	hs := []*header{{Type: 1, Len: 0x0203}, {Type: 4, Len: 0x0506}}
+
+	l := NewBigEndianBuffer(nil)
+	WriteSlice(l, 2, hs)
+	WriteSlice(l, 1, []*header{})
+	if got, want := l.Data(), []byte{0x00, 0x02, 0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x00}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("WriteSlice() wrote %#v, want %#v", got, want)
+	}
+
+	newHeader := func() *header { return new(header) }
+	if got := ReadSlice(l, 2, newHeader); !reflect.DeepEqual(got, hs) {
+		t.Errorf("ReadSlice() = %v, want %v", got, hs)
+	}
+	if got := ReadSlice(l, 1, newHeader); got == nil || len(got) != 0 {
+		t.Errorf("ReadSlice() = %#v, want empty", got)
+	}
+	if err := l.Error(); err != nil {
+		t.Errorf("Error() = %v, want nil", err)
+	}
+
+	WriteSlice(l, 1, make([]*header, 256))
+	if err := l.Error(); !errors.Is(err, ErrOverflow) || l.Len() != 0 {
+		t.Errorf("WriteSlice() of 256 items: Error() = %v, Len() = %d, want %v, 0", err, l.Len(), ErrOverflow)
+	}
+}
+
+func TestReadSliceErrors(t *testing.T) {
+	newHeader := func() *header { return new(header) }
+
+	// A truncated element.
+	l := NewBigEndianBuffer([]byte{0x02, 0x01, 0x02, 0x03, 0x04})
+	if got := ReadSlice(l, 1, newHeader); got != nil {
+		t.Errorf("ReadSlice() = %v, want nil", got)
+	}
+	if errs := l.Errors(); len(errs) != 1 || !errors.Is(errs[0], io.ErrUnexpectedEOF) {
+		t.Errorf("Errors() = %v, want one %v", errs, io.ErrUnexpectedEOF)
+	}
+
+	// An error returned only by Unmarshal.
+	l = NewBigEndianBuffer([]byte{0x01})
+	if got := ReadSlice(l, 1, func() *errUnmarshaler { return new(errUnmarshaler) }); got != nil {
+		t.Errorf("ReadSlice() = %v, want nil", got)
+	}
+	if err := l.Error(); !errors.Is(err, io.ErrNoProgress) {
+		t.Errorf("Error() = %v, want %v", err, io.ErrNoProgress)
+	}
+
+	// A huge count is rejected before anything is allocated.
+	l = NewBigEndianBuffer([]byte{0xff, 0xff, 0xff, 0xff})
+	l.SetMaxAlloc(1 << 20)
+	if got := ReadSlice(l, 4, newHeader); got != nil {
+		t.Errorf("ReadSlice() = %v, want nil", got)
+	}
+	if err := l.Error(); !errors.Is(err, ErrTooLong) {
+		t.Errorf("Error() = %v, want %v", err, ErrTooLong)
+	}
+}
+
+type entry struct {
+	Type  uint8
+	Flags uint8
+	Addr  uint32
+}
+
+func TestReadStructSlice(t *testing.T) {
+	l := NewLittleEndianBuffer([]byte{
+		0x02,
+		0x01, 0x80, 0x00, 0x10, 0x00, 0x00,
+		0x02, 0x00, 0x04, 0x03, 0x02, 0x01,
+		0xff,
+	})
+	n := l.Read8()
+	got := ReadStructSlice[entry](l, int(n))
+	want := []entry{{Type: 1, Flags: 0x80, Addr: 0x1000}, {Type: 2, Addr: 0x01020304}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ReadStructSlice() = %#v, want %#v", got, want)
+	}
+	if got := ReadStructSlice[entry](l, 0); got == nil || len(got) != 0 {
+		t.Errorf("ReadStructSlice(0) = %#v, want empty", got)
+	}
+	if err := l.Error(); err != nil {
+		t.Errorf("Error() = %v, want nil", err)
+	}
+
+	if got := ReadStructSlice[entry](l, 1); got != nil {
+		t.Errorf("ReadStructSlice() past the end = %#v, want nil", got)
+	}
+	if err := l.Error(); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("Error() = %v, want %v", err, io.ErrUnexpectedEOF)
+	}
+	if l.Len() != 1 {
+		t.Errorf("Len() = %d, want 1", l.Len())
+	}
+
+	l = NewLittleEndianBuffer(make([]byte, 16))
+	if got := ReadStructSlice[[]int](l, 1); got != nil {
+		t.Errorf("ReadStructSlice[[]int]() = %#v, want nil", got)
+	}
+	if err := l.Error(); !errors.Is(err, ErrUnsupportedType) {
+		t.Errorf("Error() = %v, want %v", err, ErrUnsupportedType)
+	}
+
+	l = NewLittleEndianBuffer(make([]byte, 16))
+	l.SetMaxAlloc(8)
+	if got := ReadStructSlice[entry](l, 2); got != nil {
+		t.Errorf("ReadStructSlice() over the limit = %#v, want nil", got)
+	}
+	if err := l.Error(); !errors.Is(err, ErrTooLong) {
+		t.Errorf("Error() = %v, want %v", err, ErrTooLong)
+	}
+
+	// count*8 wraps around to 0 with a 64-bit int.
+	huge := int(uint64(maxInt)>>1 + 1)
+	l = NewLittleEndianBuffer(make([]byte, 16))
+	l.SetMaxAlloc(100)
+	if got := ReadStructSlice[uint64](l, huge); got != nil {
+		t.Errorf("ReadStructSlice(%d) = %#v, want nil", huge, got)
+	}
+	if err := l.Error(); !errors.Is(err, ErrTooLong) {
+		t.Errorf("ReadStructSlice(%d): Error() = %v, want %v", huge, err, ErrTooLong)
+	}
+}
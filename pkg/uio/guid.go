@@ -0,0 +1,56 @@
+// Copyright 2018 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package uio
+
+// swizzleGUID converts between the canonical and the mixed-endian GUID
+// layout, by reversing the bytes of the first three fields.
+func swizzleGUID(g [16]byte) [16]byte {
+	g[0], g[1], g[2], g[3] = g[3], g[2], g[1], g[0]
+	g[4], g[5] = g[5], g[4]
+	g[6], g[7] = g[7], g[6]
+	return g
+}
+
+// ReadGUID reads a 16-byte GUID stored in the canonical RFC 4122 byte order,
+// all fields big endian, regardless of the Lexer's byte order. In canonical
+// order, the bytes appear in the same order as the hex digits of the GUID's
+// string form: C12A7328-F81F-... is {0xc1, 0x2a, 0x73, 0x28, 0xf8, 0x1f, ...}.
+//
+// GPT, UEFI and SMBIOS store GUIDs in the mixed-endian layout instead; use
+// ReadMixedEndianGUID for those.
+//
+// If an error occurred, Error() will return a non-nil error.
+func (l *Lexer) ReadGUID() [16]byte {
+	var g [16]byte
+	copy(g[:], l.consume(16))
+	return g
+}
+
+// ReadMixedEndianGUID reads a 16-byte GUID stored in the Microsoft
+// mixed-endian layout used by GPT, UEFI and SMBIOS, where the first three
+// fields (4, 2 and 2 bytes) are little endian and the last 8 bytes are in
+// order. It returns the GUID in canonical byte order, regardless of the
+// Lexer's byte order.
+//
+// If an error occurred, Error() will return a non-nil error.
+func (l *Lexer) ReadMixedEndianGUID() [16]byte {
+	return swizzleGUID(l.ReadGUID())
+}
+
+// WriteGUID writes g, in canonical byte order, as is.
+//
+// If an error occurred, Error() will return a non-nil error.
+func (l *Lexer) WriteGUID(g [16]byte) {
+	l.WriteBytes(g[:])
+}
+
+// WriteMixedEndianGUID writes g, in canonical byte order, in the
+// mixed-endian layout read by ReadMixedEndianGUID.
+//
+// If an error occurred, Error() will return a non-nil error.
+func (l *Lexer) WriteMixedEndianGUID(g [16]byte) {
+	g = swizzleGUID(g)
+	l.WriteBytes(g[:])
+}
@@ -0,0 +1,48 @@
+// Copyright 2018 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package uio
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"reflect"
+	"testing"
+)
+
+// The GPT EFI System Partition type GUID, C12A7328-F81F-11D2-BA4B-00A0C93EC93B.
+var espGUID = [16]byte{0xc1, 0x2a, 0x73, 0x28, 0xf8, 0x1f, 0x11, 0xd2, 0xba, 0x4b, 0x00, 0xa0, 0xc9, 0x3e, 0xc9, 0x3b}
+
+func TestGUID(t *testing.T) {
+	// As it appears in a GPT partition entry.
+	onDisk := []byte{0x28, 0x73, 0x2a, 0xc1, 0x1f, 0xf8, 0xd2, 0x11, 0xba, 0x4b, 0x00, 0xa0, 0xc9, 0x3e, 0xc9, 0x3b}
+
+	l := NewBigEndianBuffer(nil)
+	l.WriteMixedEndianGUID(espGUID)
+	l.WriteGUID(espGUID)
+	if got, want := l.Data(), append(append([]byte(nil), onDisk...), espGUID[:]...); !reflect.DeepEqual(got, want) {
+		t.Errorf("Data() = %#v, want %#v", got, want)
+	}
+
+	// The byte order of the Lexer does not matter.
+	l.SetOrder(binary.LittleEndian)
+	if got := l.ReadMixedEndianGUID(); got != espGUID {
+		t.Errorf("ReadMixedEndianGUID() = %#v, want %#v", got, espGUID)
+	}
+	if got := l.ReadGUID(); got != espGUID {
+		t.Errorf("ReadGUID() = %#v, want %#v", got, espGUID)
+	}
+	if err := l.Error(); err != nil {
+		t.Errorf("Error() = %v, want nil", err)
+	}
+
+	l = NewLittleEndianBuffer(onDisk[:15])
+	if got := l.ReadGUID(); got != [16]byte{} {
+		t.Errorf("ReadGUID() of 15 bytes = %#v, want zero GUID", got)
+	}
+	if err := l.Error(); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("Error() = %v, want %v", err, io.ErrUnexpectedEOF)
+	}
+}
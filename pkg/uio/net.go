@@ -0,0 +1,79 @@
+// Copyright 2018 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package uio
+
+import (
+	"errors"
+	"net"
+)
+
+// ErrInvalidAddress is set when writing an address of the wrong form.
+var ErrInvalidAddress = errors.New("invalid address")
+
+// ReadIPv4 reads a 4-byte IPv4 address from the Buffer.
+//
+// Addresses are always in network byte order, regardless of the Lexer's
+// byte order.
+//
+// If an error occurred, Error() will return a non-nil error.
+func (l *Lexer) ReadIPv4() net.IP {
+	return net.IP(l.CopyN(net.IPv4len))
+}
+
+// ReadIPv6 reads a 16-byte IPv6 address from the Buffer.
+//
+// Addresses are always in network byte order, regardless of the Lexer's
+// byte order.
+//
+// If an error occurred, Error() will return a non-nil error.
+func (l *Lexer) ReadIPv6() net.IP {
+	return net.IP(l.CopyN(net.IPv6len))
+}
+
+// WriteIPv4 writes ip to the Buffer as a 4-byte IPv4 address.
+//
+// If ip is not an IPv4 address, nothing is written and Error() will return
+// ErrInvalidAddress.
+func (l *Lexer) WriteIPv4(ip net.IP) {
+	ip4 := ip.To4()
+	if ip4 == nil {
+		l.setError(ErrInvalidAddress)
+		return
+	}
+	l.WriteBytes(ip4)
+}
+
+// WriteIPv6 writes ip to the Buffer as a 16-byte IPv6 address.
+//
+// IPv4 addresses are written in their IPv4-mapped form. If ip is not a valid
+// address, nothing is written and Error() will return ErrInvalidAddress.
+func (l *Lexer) WriteIPv6(ip net.IP) {
+	ip16 := ip.To16()
+	if ip16 == nil {
+		l.setError(ErrInvalidAddress)
+		return
+	}
+	l.WriteBytes(ip16)
+}
+
+// ReadHardwareAddr reads an n-byte hardware address from the Buffer, e.g. 6
+// bytes for Ethernet, 8 for EUI-64 or 20 for InfiniBand.
+//
+// If an error occurred, Error() will return a non-nil error.
+func (l *Lexer) ReadHardwareAddr(n int) net.HardwareAddr {
+	return net.HardwareAddr(l.CopyN(n))
+}
+
+// WriteHardwareAddr writes addr to the Buffer as an n-byte hardware address.
+//
+// If addr is not n bytes long, nothing is written and Error() will return
+// ErrInvalidAddress.
+func (l *Lexer) WriteHardwareAddr(addr net.HardwareAddr, n int) {
+	if len(addr) != n {
+		l.setError(ErrInvalidAddress)
+		return
+	}
+	l.WriteBytes(addr)
+}
@@ -0,0 +1,98 @@
+// Copyright 2018 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package uio
+
+import (
+	"errors"
+	"io"
+	"net"
+	"reflect"
+	"testing"
+)
+
+func TestIP(t *testing.T) {
+	// IP addresses don't depend on the Lexer's byte order.
+	l := NewLittleEndianBuffer(nil)
+	l.WriteIPv4(net.IPv4(192, 168, 0, 1))
+	l.WriteIPv6(net.ParseIP("fe80::1"))
+	l.WriteIPv6(net.IPv4(10, 0, 0, 1))
+
+	want := []byte{
+		192, 168, 0, 1,
+		0xfe, 0x80, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 1,
+		0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0xff, 0xff, 10, 0, 0, 1,
+	}
+	if got := l.Data(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("Data() = %#v, want %#v", got, want)
+	}
+
+	if got, want := l.ReadIPv4(), net.IPv4(192, 168, 0, 1); !got.Equal(want) || len(got) != net.IPv4len {
+		t.Errorf("ReadIPv4() = %v, want %v", got, want)
+	}
+	if got, want := l.ReadIPv6(), net.ParseIP("fe80::1"); !got.Equal(want) {
+		t.Errorf("ReadIPv6() = %v, want %v", got, want)
+	}
+	if got, want := l.ReadIPv6(), net.IPv4(10, 0, 0, 1); !got.Equal(want) {
+		t.Errorf("ReadIPv6() = %v, want %v", got, want)
+	}
+	if err := l.Error(); err != nil {
+		t.Errorf("Error() = %v, want nil", err)
+	}
+
+	if got := l.ReadIPv4(); got != nil {
+		t.Errorf("ReadIPv4() on empty buffer = %v, want nil", got)
+	}
+	if err := l.Error(); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("Error() = %v, want %v", err, io.ErrUnexpectedEOF)
+	}
+}
+
+func TestWriteIPErrors(t *testing.T) {
+	for _, ip := range []net.IP{nil, net.ParseIP("fe80::1"), net.IP{1, 2, 3}} {
+		l := NewBigEndianBuffer(nil)
+		l.WriteIPv4(ip)
+		if err := l.Error(); !errors.Is(err, ErrInvalidAddress) {
+			t.Errorf("WriteIPv4(%v): Error() = %v, want %v", ip, err, ErrInvalidAddress)
+		}
+		if l.Len() != 0 {
+			t.Errorf("WriteIPv4(%v) wrote %d bytes, want 0", ip, l.Len())
+		}
+	}
+
+	l := NewBigEndianBuffer(nil)
+	l.WriteIPv6(net.IP{1, 2, 3})
+	if err := l.Error(); !errors.Is(err, ErrInvalidAddress) {
+		t.Errorf("WriteIPv6: Error() = %v, want %v", err, ErrInvalidAddress)
+	}
+}
+
+func TestHardwareAddr(t *testing.T) {
+	mac := net.HardwareAddr{0x00, 0x11, 0x22, 0x33, 0x44, 0x55}
+	eui64 := net.HardwareAddr{0x00, 0x11, 0x22, 0xff, 0xfe, 0x33, 0x44, 0x55}
+
+	l := NewLittleEndianBuffer(nil)
+	l.WriteHardwareAddr(mac, 6)
+	l.WriteHardwareAddr(eui64, 8)
+	if got, want := l.Data(), append(append([]byte{}, mac...), eui64...); !reflect.DeepEqual(got, want) {
+		t.Fatalf("Data() = %#v, want %#v", got, want)
+	}
+	if got := l.ReadHardwareAddr(6); !reflect.DeepEqual(got, mac) {
+		t.Errorf("ReadHardwareAddr(6) = %v, want %v", got, mac)
+	}
+	if got := l.ReadHardwareAddr(8); !reflect.DeepEqual(got, eui64) {
+		t.Errorf("ReadHardwareAddr(8) = %v, want %v", got, eui64)
+	}
+	if err := l.Error(); err != nil {
+		t.Errorf("Error() = %v, want nil", err)
+	}
+
+	l.WriteHardwareAddr(eui64, 6)
+	if err := l.Error(); !errors.Is(err, ErrInvalidAddress) {
+		t.Errorf("WriteHardwareAddr with wrong length: Error() = %v, want %v", err, ErrInvalidAddress)
+	}
+	if l.Len() != 0 {
+		t.Errorf("WriteHardwareAddr with wrong length wrote %d bytes, want 0", l.Len())
+	}
+}
@@ -0,0 +1,93 @@
+// Copyright 2018 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package uio
+
+import (
+	"strconv"
+	"strings"
+)
+
+// pathElem is an element of the field path pushed by PushField or
+// PushIndex.
+type pathElem struct {
+	// name is the pushed field name, or empty for an index.
+	name string
+
+	// index is the pushed index, if name is empty.
+	index int
+
+	// field is the field name set by Field before the push, restored by
+	// PopField.
+	field string
+}
+
+// PushField adds name to the path of the current field, so that errors in
+// nested structures say where they occurred, e.g. for a DHCP packet:
+//
+//	l.PushField("packet")
+//	l.PushField("options")
+//	for i := range p.Options {
+//		l.PushIndex(i)
+//		p.Options[i].Unmarshal(l) // calls l.Field("routerIP")
+//		l.PopField()
+//	}
+//	l.PopField()
+//	l.PopField()
+//
+// An error while reading routerIP of the fourth option then has the Field
+// "packet.options[3].routerIP". The path is only turned into a string when
+// an error is set.
+//
+// The name set by Field is saved and cleared, and restored by the matching
+// PopField.
+func (l *Lexer) PushField(name string) {
+	l.path = append(l.path, pathElem{name: name, field: l.field})
+	l.field = ""
+}
+
+// PushIndex adds an index, written as "[i]", to the path of the current
+// field, like PushField.
+func (l *Lexer) PushIndex(i int) {
+	l.path = append(l.path, pathElem{index: i, field: l.field})
+	l.field = ""
+}
+
+// PopField removes the last name or index added by PushField or PushIndex
+// and restores the field name set before it. It does nothing if the path is
+// empty.
+func (l *Lexer) PopField() {
+	if len(l.path) == 0 {
+		return
+	}
+	e := l.path[len(l.path)-1]
+	l.path = l.path[:len(l.path)-1]
+	l.field = e.field
+}
+
+// fieldPath returns the path of the current field, e.g.
+// "packet.options[3].routerIP".
+func (l *Lexer) fieldPath() string {
+	if len(l.path) == 0 {
+		return l.field
+	}
+	var b strings.Builder
+	for _, e := range l.path {
+		if e.name == "" {
+			b.WriteString("[" + strconv.Itoa(e.index) + "]")
+			continue
+		}
+		if b.Len() > 0 {
+			b.WriteByte('.')
+		}
+		b.WriteString(e.name)
+	}
+	if l.field != "" {
+		if b.Len() > 0 {
+			b.WriteByte('.')
+		}
+		b.WriteString(l.field)
+	}
+	return b.String()
+}
@@ -0,0 +1,68 @@
+// Copyright 2018 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package uio
+
+import (
+	"errors"
+	"testing"
+)
+
+type pathOption struct {
+	RouterIP uint32
+}
+
+func (o *pathOption) Unmarshal(l *Lexer) error {
+	o.RouterIP = l.Field("routerIP").Read32()
+	return l.Error()
+}
+
+func TestFieldPath(t *testing.T) {
+	l := NewBigEndianBuffer(make([]byte, 1+3*4+1))
+	l.Field("version").Read8()
+	l.PushField("packet")
+	l.PushField("options")
+	var opts [4]pathOption
+	for i := range opts {
+		l.PushIndex(i)
+		opts[i].Unmarshal(l)
+		l.PopField()
+	}
+	l.PopField()
+	l.Read8()
+	l.PopField()
+	l.Read8()
+
+	errs := l.Errors()
+	if len(errs) != 2 {
+		t.Fatalf("Errors() = %v, want 2 errors", errs)
+	}
+	for i, want := range []string{"packet.options[3].routerIP", "version"} {
+		var ferr *FieldError
+		if !errors.As(errs[i], &ferr) || ferr.Field != want {
+			t.Errorf("Errors()[%d] = %v, want error in field %q", i, errs[i], want)
+		}
+	}
+}
+
+func TestFieldPathEdges(t *testing.T) {
+	for i, tt := range []struct {
+		push func(l *Lexer)
+		want string
+	}{
+		{push: func(l *Lexer) {}, want: ""},
+		{push: func(l *Lexer) { l.PushIndex(2) }, want: "[2]"},
+		{push: func(l *Lexer) { l.PushIndex(2); l.PushIndex(0) }, want: "[2][0]"},
+		{push: func(l *Lexer) { l.PushField("a"); l.PushIndex(1); l.Field("b") }, want: "a[1].b"},
+		{push: func(l *Lexer) { l.PopField(); l.Field("x") }, want: "x"},
+	} {
+		l := NewBigEndianBuffer(nil)
+		tt.push(l)
+		l.Read8()
+		var ferr *FieldError
+		if !errors.As(l.Error(), &ferr) || ferr.Field != tt.want {
+			t.Errorf("#%d: Error() = %v, want error in field %q", i, l.Error(), tt.want)
+		}
+	}
+}
@@ -0,0 +1,38 @@
+// Copyright 2018 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package uio
+
+import (
+	"encoding/binary"
+	"sync"
+)
+
+var lexerPool = sync.Pool{
+	New: func() interface{} {
+		return NewLexer(NewBuffer(nil), nil)
+	},
+}
+
+// GetLexer returns an empty Lexer with the given byte order, reusing one
+// returned by PutLexer if possible.
+func GetLexer(order binary.ByteOrder) *Lexer {
+	l := lexerPool.Get().(*Lexer)
+	l.order = order
+	return l
+}
+
+// PutLexer resets l and returns it to the pool used by GetLexer.
+//
+// l, and any slice previously returned by it (including Data()), must not be
+// used after calling PutLexer.
+func PutLexer(l *Lexer) {
+	b := l.Buffer
+	b.Reset()
+	b.counting = false
+	b.ring = 0
+	b.scratch = nil
+	*l = Lexer{Buffer: b}
+	lexerPool.Put(l)
+}
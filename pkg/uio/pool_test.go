@@ -0,0 +1,95 @@
+// Copyright 2018 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package uio
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"reflect"
+	"testing"
+)
+
+func TestPoolNoStaleData(t *testing.T) {
+	l := GetLexer(binary.BigEndian)
+	l.WriteBytes([]byte{0xde, 0xad, 0xbe, 0xef})
+	l.Read8()
+	l.Read32()
+	if l.Error() == nil {
+		t.Fatalf("expected an error reading past the end")
+	}
+	PutLexer(l)
+
+	l = GetLexer(binary.LittleEndian)
+	defer PutLexer(l)
+	if l.Len() != 0 || l.Tell() != 0 || l.Error() != nil || len(l.Errors()) != 0 {
+		t.Errorf("GetLexer() = {Len: %d, Tell: %d, Error: %v}, want empty Lexer", l.Len(), l.Tell(), l.Error())
+	}
+	if l.Order() != binary.LittleEndian {
+		t.Errorf("Order() = %v, want %v", l.Order(), binary.LittleEndian)
+	}
+	l.Append(2)
+	l.Write16(0x0102)
+	if got, want := l.Data(), []byte{0x00, 0x00, 0x02, 0x01}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Data() = %#v, want %#v", got, want)
+	}
+}
+
+func TestPoolResetsModes(t *testing.T) {
+	l := GetLexer(binary.BigEndian)
+	l.Buffer = NewRingBuffer(2)
+	l.SetMaxSize(1)
+	l.SetStrict(true)
+	l.Tap(sha256.New())
+	l.Field("a").Write32(1)
+	PutLexer(l)
+
+	l = GetLexer(binary.LittleEndian)
+	defer PutLexer(l)
+	l.Append(2)
+	l.Write16(0x0102)
+	if got, want := l.Data(), []byte{0x00, 0x00, 0x02, 0x01}; !reflect.DeepEqual(got, want) || l.Error() != nil {
+		t.Errorf("Data() = %#v, %v, want %#v, nil", got, l.Error(), want)
+	}
+}
+
+func TestBufferReset(t *testing.T) {
+	b := NewBuffer(make([]byte, 8, 32))
+	b.ReadN(3)
+	b.Reset()
+	if b.Len() != 0 || b.Tell() != 0 {
+		t.Errorf("after Reset: Len() = %d, Tell() = %d, want 0, 0", b.Len(), b.Tell())
+	}
+	if b.Cap() != 32 {
+		t.Errorf("after Reset: Cap() = %d, want 32", b.Cap())
+	}
+}
+
+func marshalMessage(l *Lexer) {
+	l.Write8(1)
+	l.Write8(1)
+	l.Write8(6)
+	l.Write8(0)
+	l.Write32(0xdeadbeef)
+	l.Write16(0)
+	l.Write16(0x8000)
+	l.WriteBytes(make([]byte, 200))
+}
+
+func BenchmarkMarshal(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		l := NewBigEndianBuffer(nil)
+		marshalMessage(l)
+	}
+}
+
+func BenchmarkMarshalPool(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		l := GetLexer(binary.BigEndian)
+		marshalMessage(l)
+		PutLexer(l)
+	}
+}
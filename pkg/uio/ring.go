@@ -0,0 +1,50 @@
+// Copyright 2018 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package uio
+
+// NewRingBuffer returns an empty Buffer that holds at most capacity bytes,
+// consumed or not: once it is full, each write drops the oldest bytes to
+// make room, e.g. to keep the tail of a serial console log in bounded
+// memory. Reads see the bytes still held, in the order they were written.
+//
+// Positions, such as those returned by Tell, Lexer.Mark and
+// Lexer.WriteMark, count from the oldest byte still held. When bytes are
+// dropped, the positions of the remaining bytes decrease by the number
+// dropped, and positions saved earlier no longer refer to the same bytes.
+// If unconsumed bytes are dropped, they are lost and reading resumes at the
+// oldest byte left.
+//
+// A single write larger than capacity drops all older bytes and is kept
+// whole until the next write, except that Write and ReadFrom, which are used
+// by io.Copy, only keep the last capacity bytes.
+//
+// NewRingBuffer panics if capacity is not positive.
+func NewRingBuffer(capacity int) *Buffer {
+	if capacity < 1 {
+		panic("uio: ring buffer capacity must be positive")
+	}
+	return &Buffer{
+		data: make([]byte, 0, capacity),
+		ring: capacity,
+	}
+}
+
+// dropOldest discards the first n bytes of the Buffer, or all of them if it
+// holds fewer.
+func (b *Buffer) dropOldest(n int) {
+	if n <= 0 {
+		return
+	}
+	if n > len(b.data) {
+		n = len(b.data)
+	}
+	// Reslicing rather than copying keeps writes cheap; append moves the
+	// remaining bytes to a new array once the old one is used up.
+	b.data = b.data[n:]
+	b.off -= n
+	if b.off < 0 {
+		b.off = 0
+	}
+}
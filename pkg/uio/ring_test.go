@@ -0,0 +1,136 @@
+// Copyright 2018 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package uio
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"io"
+	"reflect"
+	"testing"
+)
+
+func TestRingBuffer(t *testing.T) {
+	b := NewRingBuffer(8)
+	l := NewLexer(b, binary.BigEndian)
+	l.WriteString("abcde")
+	if got, want := string(l.CopyN(2)), "ab"; got != want {
+		t.Errorf("CopyN(2) = %q, want %q", got, want)
+	}
+
+	// Drops "ab", which were consumed, and "c", which was not.
+	l.WriteString("fghijk")
+	if got, want := string(l.Data()), "defghijk"; got != want {
+		t.Errorf("Data() after wrap = %q, want %q", got, want)
+	}
+	if got, want := l.Tell(), 0; got != want {
+		t.Errorf("Tell() after wrap = %d, want %d", got, want)
+	}
+	if got, want := string(l.ReadAll()), "defghijk"; got != want {
+		t.Errorf("ReadAll() = %q, want %q", got, want)
+	}
+
+	l.WriteString("lm")
+	if got, want := l.Tell(), 6; got != want {
+		t.Errorf("Tell() after dropping 2 consumed bytes = %d, want %d", got, want)
+	}
+	if got, want := string(l.Data()), "lm"; got != want {
+		t.Errorf("Data() = %q, want %q", got, want)
+	}
+	if got, want := string(b.data), "fghijklm"; got != want {
+		t.Errorf("held bytes = %q, want %q", got, want)
+	}
+
+	// A write larger than the capacity replaces everything.
+	l.WriteString("0123456789")
+	if got, want := string(b.data), "0123456789"; got != want {
+		t.Errorf("held bytes after a large write = %q, want %q", got, want)
+	}
+	if got, want := l.Tell(), 0; got != want {
+		t.Errorf("Tell() after a large write = %d, want %d", got, want)
+	}
+	l.Write8('x')
+	if got, want := string(l.Data()), "3456789x"; got != want {
+		t.Errorf("Data() = %q, want %q", got, want)
+	}
+	if err := l.Error(); err != nil {
+		t.Errorf("Error() = %v, want nil", err)
+	}
+
+}
+
+func TestRingBufferCopy(t *testing.T) {
+	src := bytes.Repeat([]byte("0123456789"), 1000)
+	for _, tt := range []struct {
+		name string
+		r    io.Reader
+	}{
+		// bytes.Reader implements io.WriterTo, so io.Copy calls Write.
+		{"Write", bytes.NewReader(src)},
+		{"ReadFrom", struct{ io.Reader }{bytes.NewReader(src)}},
+	} {
+		l := NewLexer(NewRingBuffer(16), binary.BigEndian)
+		if n, err := io.Copy(l, tt.r); n != int64(len(src)) || err != nil {
+			t.Errorf("%s: io.Copy() = %d, %v, want %d, nil", tt.name, n, err, len(src))
+		}
+		if got, want := l.Data(), src[len(src)-16:]; !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: Data() after io.Copy() = %q, want %q", tt.name, got, want)
+		}
+		if err := l.Error(); err != nil {
+			t.Errorf("%s: Error() = %v, want nil", tt.name, err)
+		}
+	}
+}
+
+func TestRingBufferTap(t *testing.T) {
+	src := []byte("0123456789")
+	want := sha256.Sum256(src)
+	for _, tt := range []struct {
+		name string
+		r    io.Reader
+	}{
+		{"Write", bytes.NewReader(src)},
+		{"ReadFrom", struct{ io.Reader }{bytes.NewReader(src)}},
+	} {
+		l := NewLexer(NewRingBuffer(4), binary.BigEndian)
+		h := sha256.New()
+		l.Tap(h)
+		if _, err := io.Copy(l, tt.r); err != nil {
+			t.Errorf("%s: io.Copy() = %v", tt.name, err)
+		}
+		l.Untap()
+		if got := h.Sum(nil); !bytes.Equal(got, want[:]) {
+			t.Errorf("%s: tap digest after io.Copy() = %x, want %x", tt.name, got, want)
+		}
+	}
+}
+
+func TestRingBufferWriteAt(t *testing.T) {
+	b := NewRingBuffer(4)
+	b.WriteAt([]byte{1, 2, 3}, 0)
+	if n, err := b.WriteAt([]byte{4, 5, 6}, 2); n != 3 || err != nil {
+		t.Errorf("WriteAt() = %d, %v, want 3, nil", n, err)
+	}
+	if got, want := b.Data(), []byte{2, 4, 5, 6}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Data() = %#v, want %#v", got, want)
+	}
+	if n, err := b.WriteAt([]byte{7, 8, 9}, 6); n != 3 || err != nil {
+		t.Errorf("WriteAt() = %d, %v, want 3, nil", n, err)
+	}
+	// The zero-filled gap and p make a single write larger than capacity.
+	if got, want := b.Data(), []byte{0, 0, 7, 8, 9}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Data() = %#v, want %#v", got, want)
+	}
+}
+
+func TestNewRingBufferPanics(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Errorf("NewRingBuffer(0) did not panic")
+		}
+	}()
+	NewRingBuffer(0)
+}
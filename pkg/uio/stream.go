@@ -0,0 +1,193 @@
+// Copyright 2018 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package uio
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"io"
+)
+
+// NewReaderLexer returns a Lexer that lazily reads its data from r.
+//
+// Reads from r happen only when a method needs more bytes than are
+// buffered, so a header can be parsed without reading the rest of r.
+//
+// Data() and Len() only describe bytes already read from r, while methods
+// that consume data read as much as they need. Like any Buffer, consumed
+// bytes are kept so that Tell, Mark and Rewind work as usual; memory use
+// therefore grows with the number of bytes read so far.
+//
+// If r returns an error other than io.EOF, it becomes the Lexer's error.
+func NewReaderLexer(r io.Reader, order binary.ByteOrder) *Lexer {
+	return NewLexer(&Buffer{r: r}, order)
+}
+
+// fill reads from the Buffer's io.Reader, if any, until at least n bytes are
+// unconsumed or the reader returns an error.
+func (b *Buffer) fill(n int) {
+	for b.r != nil && b.rerr == nil && b.Len() < n {
+		b.grow(minRead)
+		m, err := b.r.Read(b.data[len(b.data):cap(b.data)])
+		b.data = b.data[:len(b.data)+m]
+		b.rerr = err
+	}
+}
+
+// fillContext is like fill, but gives up when ctx is done.
+//
+// Since a blocked Read cannot be interrupted, it is left running in the
+// background and its result discarded; ctx.Err() then becomes the reader's
+// error, so that r is not read from again.
+func (b *Buffer) fillContext(ctx context.Context, n int) error {
+	for b.r != nil && b.rerr == nil && b.Len() < n {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		type result struct {
+			n   int
+			err error
+		}
+		p := make([]byte, minRead)
+		done := make(chan result, 1)
+		go func(r io.Reader) {
+			m, err := r.Read(p)
+			done <- result{m, err}
+		}(b.r)
+		select {
+		case res := <-done:
+			b.data = append(b.data, p[:res.n]...)
+			b.rerr = res.err
+		case <-ctx.Done():
+			b.rerr = ctx.Err()
+			return b.rerr
+		}
+	}
+	return nil
+}
+
+// ReadContext consumes and returns the next n bytes, like ReadN, reading
+// from the Lexer's io.Reader as needed until ctx is done, e.g. so that a
+// stalled network source cannot block the caller forever:
+//
+//	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+//	defer cancel()
+//	hdr := NewBigEndianBuffer(l.ReadContext(ctx, headerLen))
+//
+// Lexers not backed by an io.Reader never block and ignore ctx.
+//
+// If ctx is done before n bytes are read, nothing is consumed and Error()
+// will return ctx.Err(). If a Read was in progress, it becomes the error of
+// all later reads, as the io.Reader is no longer used.
+func (l *Lexer) ReadContext(ctx context.Context, n int) []byte {
+	if n < 0 {
+		l.setError(ErrInvalidSize)
+		return nil
+	}
+	if err := l.fillContext(ctx, n); err != nil {
+		l.setError(err)
+		return nil
+	}
+	return l.consume(n)
+}
+
+// indexByte returns the index of the first c in the first limit unconsumed
+// bytes, or -1 if there is none. More data is read from the Buffer's
+// io.Reader as needed.
+func (b *Buffer) indexByte(c byte, limit int) int {
+	for searched := 0; ; {
+		d := b.Data()
+		if len(d) > limit {
+			d = d[:limit]
+		}
+		if i := bytes.IndexByte(d[searched:], c); i >= 0 {
+			return searched + i
+		}
+		searched = len(d)
+		if searched == limit || !b.Has(searched+1) {
+			return -1
+		}
+	}
+}
+
+// DefaultChunkSize is the chunk size used by MarshalTo.
+const DefaultChunkSize = 64 << 10
+
+// chunkSink is where a Lexer used by MarshalTo flushes its chunks.
+type chunkSink struct {
+	w    io.Writer
+	size int
+
+	// n is the number of bytes written to w so far, and err the first
+	// error w returned.
+	n   int
+	err error
+
+	// flushed is the number of bytes flushed, written to w or not, and so
+	// the write position of the start of the current chunk.
+	flushed int
+}
+
+// MarshalTo marshals m in the given byte order to w, in chunks of
+// DefaultChunkSize bytes, e.g. to write a large filesystem image without
+// holding all of it in memory. It returns the number of bytes written to w.
+//
+// See MarshalToSize for restrictions on m.
+func MarshalTo(w io.Writer, order binary.ByteOrder, m Marshaler) (int, error) {
+	return MarshalToSize(w, order, m, DefaultChunkSize)
+}
+
+// MarshalToSize is like MarshalTo, but with chunks of size bytes, or of
+// DefaultChunkSize bytes if size is not positive.
+//
+// Bytes are buffered until the next write would grow the chunk beyond size,
+// and then written to w and discarded; a single write larger than size is
+// buffered whole. Write positions, such as those returned by WriteMark and
+// Checkpoint, still count from the first byte marshaled, but once a chunk is
+// flushed its bytes can no longer be changed: Rollback, RegionSince,
+// PlaceLength16 and the other methods that take a write position set
+// ErrInvalidOffset if it is in a flushed chunk. Read positions and
+// Buffer.WriteAt count from the start of the current chunk.
+//
+// If marshaling sets an error, the rest of the current chunk is not written
+// and the error is returned. If w returns an error, later chunks are
+// discarded and it is returned.
+func MarshalToSize(w io.Writer, order binary.ByteOrder, m Marshaler, size int) (int, error) {
+	if size < 1 {
+		size = DefaultChunkSize
+	}
+	l := NewLexer(NewBufferSize(size), order)
+	l.sink = &chunkSink{w: w, size: size}
+	m.Marshal(l)
+	if err := l.Error(); err != nil {
+		return l.sink.n, err
+	}
+	l.flush()
+	return l.sink.n, l.sink.err
+}
+
+// flush writes the Buffer to the Lexer's sink and empties it.
+func (l *Lexer) flush() {
+	if l.tap != nil {
+		l.flushTap()
+	}
+	if l.sink.err == nil {
+		n, err := l.sink.w.Write(l.data)
+		l.sink.n += n
+		if err == nil && n < len(l.data) {
+			err = io.ErrShortWrite
+		}
+		if err != nil {
+			l.sink.err = err
+			l.setError(err)
+		}
+	}
+	// w must not retain the bytes, so the chunk can be reused.
+	l.sink.flushed += len(l.data)
+	l.data = l.data[:0]
+	l.off = 0
+	l.tapped = 0
+}
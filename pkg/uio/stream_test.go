@@ -0,0 +1,270 @@
+// Copyright 2018 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package uio
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"io/ioutil"
+	"reflect"
+	"testing"
+	"testing/iotest"
+	"time"
+)
+
+func TestReaderLexer(t *testing.T) {
+	content := []byte("\x01\x02\x03\x04hello\x00world\nrest")
+	r := &countingReader{r: iotest.OneByteReader(bytes.NewReader(content))}
+	l := NewReaderLexer(r, binary.BigEndian)
+
+	if l.Len() != 0 || r.n != 0 {
+		t.Errorf("NewReaderLexer read %d bytes up front, want 0", r.n)
+	}
+	if got, want := l.Read16(), uint16(0x0102); got != want {
+		t.Errorf("Read16() = %#x, want %#x", got, want)
+	}
+	if r.n != 2 {
+		t.Errorf("Read16() read %d bytes from the reader, want 2", r.n)
+	}
+	if got, want := l.Peek16(), uint16(0x0304); got != want {
+		t.Errorf("Peek16() = %#x, want %#x", got, want)
+	}
+	l.Skip(2)
+	if got, want := l.ReadCString(), "hello"; got != want {
+		t.Errorf("ReadCString() = %q, want %q", got, want)
+	}
+	if got, want := string(l.ReadLine()), "world"; got != want {
+		t.Errorf("ReadLine() = %q, want %q", got, want)
+	}
+	if got, want := l.Tell(), 16; got != want {
+		t.Errorf("Tell() = %d, want %d", got, want)
+	}
+	if got, want := string(l.ReadAll()), "rest"; got != want {
+		t.Errorf("ReadAll() = %q, want %q", got, want)
+	}
+	if err := l.Error(); err != nil {
+		t.Errorf("Error() = %v, want nil", err)
+	}
+
+	l.Read8()
+	if err := l.Error(); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("Read8() at EOF: Error() = %v, want %v", err, io.ErrUnexpectedEOF)
+	}
+}
+
+func TestReaderLexerRewind(t *testing.T) {
+	l := NewReaderLexer(bytes.NewReader([]byte{0x01, 0x02, 0x03}), binary.LittleEndian)
+	m := l.Mark()
+	l.Read16()
+	l.Rewind(m)
+	if got, want := l.Read32N(0), []uint32{}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Read32N(0) = %#v, want %#v", got, want)
+	}
+	if got, want := l.Read16N(1), []uint16{0x0201}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Read16N(1) after Rewind = %#v, want %#v", got, want)
+	}
+}
+
+func TestReaderLexerError(t *testing.T) {
+	r := io.MultiReader(bytes.NewReader([]byte{0x01}), &mockReader{err: io.ErrClosedPipe})
+	l := NewReaderLexer(r, binary.BigEndian)
+	if got := l.Read8(); got != 0x01 {
+		t.Errorf("Read8() = %#x, want 0x01", got)
+	}
+	l.Read8()
+	if err := l.Error(); !errors.Is(err, io.ErrClosedPipe) {
+		t.Errorf("Error() = %v, want %v", err, io.ErrClosedPipe)
+	}
+}
+
+func TestReadContext(t *testing.T) {
+	pr, pw := io.Pipe()
+	defer pw.Close()
+	go pw.Write([]byte{0x01, 0x02})
+
+	l := NewReaderLexer(pr, binary.BigEndian)
+	if got, want := l.ReadContext(context.Background(), 2), []byte{0x01, 0x02}; !reflect.DeepEqual(got, want) {
+		t.Errorf("ReadContext() = %#v, want %#v", got, want)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if got := l.ReadContext(ctx, 1); got != nil {
+		t.Errorf("ReadContext() with a cancelled context = %#v, want nil", got)
+	}
+	if err := l.Error(); !errors.Is(err, context.Canceled) {
+		t.Errorf("ReadContext() with a cancelled context: Error() = %v, want %v", err, context.Canceled)
+	}
+
+	// The pipe has no more data, so the Read blocks until the deadline.
+	l = NewReaderLexer(pr, binary.BigEndian)
+	ctx, cancel = context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if got := l.ReadContext(ctx, 1); got != nil {
+		t.Errorf("ReadContext() on a stalled reader = %#v, want nil", got)
+	}
+	if err := l.Error(); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("ReadContext() on a stalled reader: Error() = %v, want %v", err, context.DeadlineExceeded)
+	}
+	if _, err := l.Buffer.ReadN(1); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("ReadN() after a timeout = %v, want %v", err, context.DeadlineExceeded)
+	}
+
+	// In-memory Lexers ignore the context.
+	ctx, cancel = context.WithCancel(context.Background())
+	cancel()
+	l = NewBigEndianBuffer([]byte{0x03})
+	if got, want := l.ReadContext(ctx, 1), []byte{0x03}; !reflect.DeepEqual(got, want) {
+		t.Errorf("ReadContext() in memory = %#v, want %#v", got, want)
+	}
+	if err := l.Error(); err != nil {
+		t.Errorf("ReadContext() in memory: Error() = %v, want nil", err)
+	}
+}
+
+// marshalFunc is a Marshaler calling itself.
+type marshalFunc func(l *Lexer)
+
+func (f marshalFunc) Marshal(l *Lexer) {
+	f(l)
+}
+
+// chunkWriter records the size of each Write.
+type chunkWriter struct {
+	bytes.Buffer
+	writes []int
+}
+
+func (w *chunkWriter) Write(p []byte) (int, error) {
+	w.writes = append(w.writes, len(p))
+	return w.Buffer.Write(p)
+}
+
+func TestMarshalTo(t *testing.T) {
+	m := marshalFunc(func(l *Lexer) {
+		for i := 0; i < 10; i++ {
+			l.Write32(uint32(i))
+		}
+		l.WriteBytes(make([]byte, 20))
+		l.Write16(0xffff)
+	})
+	var w chunkWriter
+	n, err := MarshalToSize(&w, binary.BigEndian, m, 16)
+	if err != nil {
+		t.Fatalf("MarshalToSize() = %v", err)
+	}
+	want := ToBigEndian(m)
+	if n != len(want) || !reflect.DeepEqual(w.Bytes(), want) {
+		t.Errorf("MarshalToSize() wrote %d bytes %#v, want %d bytes %#v", n, w.Bytes(), len(want), want)
+	}
+	if got, want := w.writes, []int{16, 16, 8, 20, 2}; !reflect.DeepEqual(got, want) {
+		t.Errorf("MarshalToSize() writes = %v, want %v", got, want)
+	}
+
+	w = chunkWriter{}
+	if _, err := MarshalTo(&w, binary.BigEndian, m); err != nil || len(w.writes) != 1 {
+		t.Errorf("MarshalTo() = %v with %d writes, want nil with 1 write", err, len(w.writes))
+	}
+}
+
+func TestMarshalToReusesChunk(t *testing.T) {
+	var arrays []*byte
+	m := marshalFunc(func(l *Lexer) {
+		for i := 0; i < 10; i++ {
+			l.Write32(uint32(i))
+			arrays = append(arrays, &l.data[0])
+		}
+	})
+	if _, err := MarshalToSize(ioutil.Discard, binary.BigEndian, m, 8); err != nil {
+		t.Fatalf("MarshalToSize() = %v", err)
+	}
+	for i, p := range arrays {
+		if p != arrays[0] {
+			t.Errorf("chunk of write #%d was reallocated", i)
+		}
+	}
+}
+
+func TestMarshalToWritePositions(t *testing.T) {
+	// The length field would be flushed before the body is written.
+	m := marshalFunc(func(l *Lexer) {
+		l.WriteBytes([]byte("AAAAAA"))
+		start := l.WriteMark()
+		l.Reserve(2)
+		l.WriteBytes([]byte("0123456789"))
+		l.PlaceLength16(start)
+	})
+	var w bytes.Buffer
+	if _, err := MarshalToSize(&w, binary.BigEndian, m, 8); !errors.Is(err, ErrInvalidOffset) {
+		t.Errorf("MarshalToSize() with a flushed length field = %v, want %v", err, ErrInvalidOffset)
+	}
+
+	// Positions after a flush still count from the first byte.
+	var marks []int
+	m = marshalFunc(func(l *Lexer) {
+		l.WriteBytes([]byte("AAAAAA"))
+		l.WriteBytes([]byte("BBBB"))
+		start := l.WriteMark()
+		marks = append(marks, start)
+		l.Reserve(2)
+		l.WriteBytes([]byte("01"))
+		l.PlaceLength16(start)
+		cp := l.Checkpoint()
+		marks = append(marks, cp)
+		l.WriteBytes([]byte("xx"))
+		l.Rollback(cp)
+		l.AlignWrite(8, 0xff)
+	})
+	w.Reset()
+	if _, err := MarshalToSize(&w, binary.BigEndian, m, 8); err != nil {
+		t.Fatalf("MarshalToSize() = %v", err)
+	}
+	if want := []int{10, 14}; !reflect.DeepEqual(marks, want) {
+		t.Errorf("write positions = %v, want %v", marks, want)
+	}
+	if got, want := w.String(), "AAAAAABBBB\x00\x0201\xff\xff"; got != want {
+		t.Errorf("MarshalToSize() wrote %q, want %q", got, want)
+	}
+}
+
+func TestMarshalToErrors(t *testing.T) {
+	m := marshalFunc(func(l *Lexer) {
+		l.Write32(1)
+		l.Write32(2)
+		l.WriteFixedString("too long", 2, 0)
+	})
+	var w bytes.Buffer
+	n, err := MarshalToSize(&w, binary.LittleEndian, m, 4)
+	if !errors.Is(err, ErrTooLong) {
+		t.Errorf("MarshalToSize() = %v, want %v", err, ErrTooLong)
+	}
+	if got, want := w.Bytes(), []byte{0x01, 0x00, 0x00, 0x00}; n != 4 || !reflect.DeepEqual(got, want) {
+		t.Errorf("MarshalToSize() wrote %d bytes %#v, want 4 bytes %#v", n, got, want)
+	}
+
+	m = marshalFunc(func(l *Lexer) {
+		l.Write32(1)
+		l.Write32(2)
+	})
+	pr, pw := io.Pipe()
+	pr.Close()
+	if _, err := MarshalToSize(pw, binary.LittleEndian, m, 4); !errors.Is(err, io.ErrClosedPipe) {
+		t.Errorf("MarshalToSize() to a closed pipe = %v, want %v", err, io.ErrClosedPipe)
+	}
+}
+
+type countingReader struct {
+	r io.Reader
+	n int
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += n
+	return n, err
+}
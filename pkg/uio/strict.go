@@ -0,0 +1,41 @@
+// Copyright 2018 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package uio
+
+import (
+	"errors"
+)
+
+// ErrNonCanonical is set in strict mode when a value is not in its canonical
+// encoding.
+var ErrNonCanonical = errors.New("non-canonical encoding")
+
+// SetStrict turns strict mode on or off for all subsequent reads.
+//
+// In strict mode, every value has exactly one accepted encoding, so that two
+// different byte sequences cannot decode to the same value, e.g. when
+// verifying a signature over a parsed message. Strict mode enables these
+// checks:
+//
+//   - ReadBool rejects bytes other than 0 and 1.
+//   - ReadULEB128, ReadSLEB128, ReadZigzag32, ReadZigzag64, ReadVarBytes,
+//     ReadUvarint and ReadVarint reject values encoded in more bytes than
+//     necessary.
+//   - ReadBigIntMinimal rejects integers with leading zero bytes.
+//   - UnmarshalAll rejects data left over after the last Unmarshaler.
+//
+// Each sets ErrNonCanonical, except UnmarshalAll, which sets ErrUnreadBytes.
+// Invalid BCD digits and unpaired UTF-16 surrogates are rejected in either
+// mode, and UTF-16 has no overlong encodings.
+//
+// Lexers returned by Limit, Consumed, Remaining and Split inherit the mode.
+func (l *Lexer) SetStrict(strict bool) {
+	l.strict = strict
+}
+
+// Strict returns true if the Lexer is in strict mode.
+func (l *Lexer) Strict() bool {
+	return l.strict
+}
@@ -0,0 +1,80 @@
+// Copyright 2018 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package uio
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestStrict(t *testing.T) {
+	for i, tt := range []struct {
+		in   []byte
+		read func(l *Lexer)
+		want error
+	}{
+		{in: []byte{0x01}, read: func(l *Lexer) { l.ReadBool() }},
+		{in: []byte{0x02}, read: func(l *Lexer) { l.ReadBool() }, want: ErrNonCanonical},
+		{in: []byte{0x80, 0x01}, read: func(l *Lexer) { l.ReadULEB128() }},
+		{in: []byte{0x80, 0x00}, read: func(l *Lexer) { l.ReadULEB128() }, want: ErrNonCanonical},
+		{in: []byte{0x81, 0x00}, read: func(l *Lexer) { l.ReadZigzag32() }, want: ErrNonCanonical},
+		{in: []byte{0x82, 0x00, 'a', 'b'}, read: func(l *Lexer) { l.ReadVarBytes() }, want: ErrNonCanonical},
+		{in: []byte{0x80, 0x01}, read: func(l *Lexer) { l.ReadUvarint() }},
+		{in: []byte{0x80, 0x00}, read: func(l *Lexer) { l.ReadUvarint() }, want: ErrNonCanonical},
+		{in: []byte{0x81, 0x00}, read: func(l *Lexer) { l.ReadVarint() }, want: ErrNonCanonical},
+		{in: []byte{0x00}, read: func(l *Lexer) { l.ReadSLEB128() }},
+		{in: []byte{0xff, 0x00}, read: func(l *Lexer) { l.ReadSLEB128() }},
+		{in: []byte{0x80, 0x7f}, read: func(l *Lexer) { l.ReadSLEB128() }},
+		{in: []byte{0x80, 0x00}, read: func(l *Lexer) { l.ReadSLEB128() }, want: ErrNonCanonical},
+		{in: []byte{0xff, 0x7f}, read: func(l *Lexer) { l.ReadSLEB128() }, want: ErrNonCanonical},
+		{in: []byte{0x01, 0x00, 0x01}, read: func(l *Lexer) { l.ReadBigIntMinimal() }},
+		{in: []byte{0x02, 0x00, 0x00, 0x01}, read: func(l *Lexer) { l.ReadBigIntMinimal() }, want: ErrNonCanonical},
+		{in: []byte{0x02}, read: func(l *Lexer) { l.Limit(1).ReadBool() }},
+		{in: []byte{0x02}, read: func(l *Lexer) {
+			sub := l.Limit(1)
+			sub.ReadBool()
+			l.setError(sub.Error())
+		}, want: ErrNonCanonical},
+	} {
+		t.Run(fmt.Sprintf("Test #%02d", i), func(t *testing.T) {
+			l := NewLittleEndianBuffer(tt.in)
+			tt.read(l)
+			if err := l.Error(); err != nil {
+				t.Errorf("Error() = %v, want nil without strict mode", err)
+			}
+
+			l = NewLittleEndianBuffer(tt.in)
+			l.SetStrict(true)
+			tt.read(l)
+			if err := l.Error(); !errors.Is(err, tt.want) {
+				t.Errorf("Error() = %v, want %v in strict mode", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestStrictUnmarshalAll(t *testing.T) {
+	var h header
+	l := NewBigEndianBuffer([]byte{0x01, 0x00, 0x02, 0xff})
+	if err := UnmarshalAll(l, &h); err != nil {
+		t.Errorf("UnmarshalAll() = %v, want nil", err)
+	}
+
+	l = NewBigEndianBuffer([]byte{0x01, 0x00, 0x02, 0xff})
+	l.SetStrict(true)
+	if !l.Strict() {
+		t.Errorf("Strict() = false, want true")
+	}
+	if err := UnmarshalAll(l, &h); !errors.Is(err, ErrUnreadBytes) {
+		t.Errorf("UnmarshalAll() = %v, want %v", err, ErrUnreadBytes)
+	}
+
+	l = NewBigEndianBuffer([]byte{0x01, 0x00, 0x02})
+	l.SetStrict(true)
+	if err := UnmarshalAll(l, &h); err != nil {
+		t.Errorf("UnmarshalAll() = %v, want nil", err)
+	}
+}
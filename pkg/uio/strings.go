@@ -0,0 +1,406 @@
+// Copyright 2018 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package uio
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"unicode"
+	"unicode/utf16"
+)
+
+// ErrInvalidUTF16 is set when a UTF-16 string contains an unpaired
+// surrogate.
+var ErrInvalidUTF16 = errors.New("invalid UTF-16 string")
+
+// ReadCString reads a NUL-terminated string from the Buffer, consuming the
+// terminating NUL and returning the string without it.
+//
+// If there is no NUL in the remaining data, all of it is consumed and
+// returned, and Error() will return io.ErrUnexpectedEOF.
+func (l *Lexer) ReadCString() string {
+	return l.readCString(-1)
+}
+
+// ReadCStringMax is like ReadCString, but reads at most max bytes before the
+// terminating NUL.
+//
+// If no NUL is found within the first max+1 bytes, max bytes are consumed and
+// returned, and Error() will return ErrTooLong.
+func (l *Lexer) ReadCStringMax(max int) string {
+	if max < 0 {
+		l.setError(ErrInvalidSize)
+		return ""
+	}
+	return l.readCString(max)
+}
+
+// readCString reads a NUL-terminated string of at most max bytes, or of any
+// length if max is negative.
+func (l *Lexer) readCString(max int) string {
+	limit := maxInt
+	if max >= 0 && max < maxInt {
+		limit = max + 1
+	}
+	if i := l.indexByte(0, limit); i >= 0 {
+		s := string(l.consume(i))
+		l.consume(1)
+		return s
+	}
+	if max >= 0 && l.Has(limit) {
+		s := string(l.consume(max))
+		l.setError(ErrTooLong)
+		return s
+	}
+	s := string(l.consume(l.Len()))
+	l.setError(io.ErrUnexpectedEOF)
+	return s
+}
+
+// ReadFixedString reads an n-byte string field from the Buffer, trimming any
+// trailing NULs.
+//
+// If an error occurred, Error() will return a non-nil error.
+func (l *Lexer) ReadFixedString(n int) string {
+	return string(bytes.TrimRight(l.consume(n), "\x00"))
+}
+
+// WriteFixedString writes s to an n-byte field in the Buffer, filling the
+// remainder of the field with pad.
+//
+// If s is longer than n bytes, nothing is written and Error() will return
+// ErrTooLong.
+func (l *Lexer) WriteFixedString(s string, n int, pad byte) {
+	if len(s) > n {
+		l.setError(ErrTooLong)
+		return
+	}
+	b := l.append(n)
+	if b == nil {
+		return
+	}
+	m := copy(b, s)
+	for i := m; i < n; i++ {
+		b[i] = pad
+	}
+}
+
+// readLengthPrefixed reads a width-byte length followed by that many bytes.
+func (l *Lexer) readLengthPrefixed(width int) []byte {
+	errs := len(l.errs)
+	n := l.ReadUintN(width)
+	if len(l.errs) > errs {
+		return nil
+	}
+	return l.readPrefixed(n)
+}
+
+// readPrefixed reads a copy of the n bytes following a length prefix.
+func (l *Lexer) readPrefixed(n uint64) []byte {
+	if !l.checkAlloc64(n) {
+		return nil
+	}
+	if n > uint64(maxInt) {
+		l.setError(io.ErrUnexpectedEOF)
+		return nil
+	}
+	if !l.Has(int(n)) {
+		l.setError(io.ErrUnexpectedEOF)
+		return nil
+	}
+	return l.CopyN(int(n))
+}
+
+// writeLengthPrefixed writes the width-byte length of p followed by p.
+func (l *Lexer) writeLengthPrefixed(width int, p []byte) {
+	if width < 8 && uint64(len(p))>>(8*uint(width)) != 0 {
+		l.setError(ErrTooLong)
+		return
+	}
+	l.WriteUintN(width, uint64(len(p)))
+	l.WriteBytes(p)
+}
+
+// ReadLengthPrefixed8 reads a copy of a byte slice prefixed by its 8-bit
+// length.
+//
+// If an error occurred, Error() will return a non-nil error.
+func (l *Lexer) ReadLengthPrefixed8() []byte {
+	return l.readLengthPrefixed(1)
+}
+
+// ReadLengthPrefixed16 reads a copy of a byte slice prefixed by its 16-bit
+// length.
+//
+// If an error occurred, Error() will return a non-nil error.
+func (l *Lexer) ReadLengthPrefixed16() []byte {
+	return l.readLengthPrefixed(2)
+}
+
+// ReadLengthPrefixed32 reads a copy of a byte slice prefixed by its 32-bit
+// length.
+//
+// If an error occurred, Error() will return a non-nil error.
+func (l *Lexer) ReadLengthPrefixed32() []byte {
+	return l.readLengthPrefixed(4)
+}
+
+// ReadLengthPrefixedString8 reads a string prefixed by its 8-bit length.
+//
+// If an error occurred, Error() will return a non-nil error.
+func (l *Lexer) ReadLengthPrefixedString8() string {
+	return string(l.readLengthPrefixed(1))
+}
+
+// ReadLengthPrefixedString16 reads a string prefixed by its 16-bit length.
+//
+// If an error occurred, Error() will return a non-nil error.
+func (l *Lexer) ReadLengthPrefixedString16() string {
+	return string(l.readLengthPrefixed(2))
+}
+
+// ReadLengthPrefixedString32 reads a string prefixed by its 32-bit length.
+//
+// If an error occurred, Error() will return a non-nil error.
+func (l *Lexer) ReadLengthPrefixedString32() string {
+	return string(l.readLengthPrefixed(4))
+}
+
+// WriteLengthPrefixed8 writes p prefixed by its 8-bit length.
+//
+// If p is longer than 255 bytes, nothing is written and Error() will return
+// ErrTooLong.
+func (l *Lexer) WriteLengthPrefixed8(p []byte) {
+	l.writeLengthPrefixed(1, p)
+}
+
+// WriteLengthPrefixed16 writes p prefixed by its 16-bit length.
+//
+// If p is longer than 65535 bytes, nothing is written and Error() will
+// return ErrTooLong.
+func (l *Lexer) WriteLengthPrefixed16(p []byte) {
+	l.writeLengthPrefixed(2, p)
+}
+
+// WriteLengthPrefixed32 writes p prefixed by its 32-bit length.
+//
+// If p is longer than 2^32-1 bytes, nothing is written and Error() will
+// return ErrTooLong.
+func (l *Lexer) WriteLengthPrefixed32(p []byte) {
+	l.writeLengthPrefixed(4, p)
+}
+
+// WriteLengthPrefixedString8 writes s prefixed by its 8-bit length.
+//
+// If s is longer than 255 bytes, nothing is written and Error() will return
+// ErrTooLong.
+func (l *Lexer) WriteLengthPrefixedString8(s string) {
+	l.writeLengthPrefixed(1, []byte(s))
+}
+
+// WriteLengthPrefixedString16 writes s prefixed by its 16-bit length.
+//
+// If s is longer than 65535 bytes, nothing is written and Error() will
+// return ErrTooLong.
+func (l *Lexer) WriteLengthPrefixedString16(s string) {
+	l.writeLengthPrefixed(2, []byte(s))
+}
+
+// WriteLengthPrefixedString32 writes s prefixed by its 32-bit length.
+//
+// If s is longer than 2^32-1 bytes, nothing is written and Error() will
+// return ErrTooLong.
+func (l *Lexer) WriteLengthPrefixedString32(s string) {
+	l.writeLengthPrefixed(4, []byte(s))
+}
+
+// ReadLengthPrefixedInto reads a lenWidth-byte length and unmarshals u from
+// exactly that many bytes, using a Lexer returned by Limit, e.g. for a
+// nested record whose length is declared by its parent:
+//
+//	if err := l.ReadLengthPrefixedInto(2, &ext); err != nil {
+//		return err
+//	}
+//
+// If u reads past the declared length, or leaves some of it unread, the error
+// wraps io.ErrUnexpectedEOF or ErrUnreadBytes respectively. Either way, all
+// of the declared length is consumed from l.
+//
+// It returns the first error set by this call, which Error() will also
+// return if it is the first error in l.
+func (l *Lexer) ReadLengthPrefixedInto(lenWidth int, u Unmarshaler) error {
+	errs := len(l.errs)
+	if n := l.ReadUintN(lenWidth); len(l.errs) == errs {
+		switch {
+		case !l.checkAlloc64(n):
+		case n > uint64(maxInt) || !l.Has(int(n)):
+			l.setError(io.ErrUnexpectedEOF)
+		default:
+			sub := l.Limit(int(n))
+			err := u.Unmarshal(sub)
+			if err == nil {
+				sub.AssertFinished()
+				err = sub.Error()
+			}
+			l.setError(err)
+		}
+	}
+	if len(l.errs) > errs {
+		return l.errs[errs]
+	}
+	return nil
+}
+
+// ReadPascalString reads a Pascal string: a string prefixed by its 8-bit
+// length.
+//
+// If an error occurred, Error() will return a non-nil error.
+func (l *Lexer) ReadPascalString() string {
+	return string(l.readLengthPrefixed(1))
+}
+
+// ReadPascalStringMax is like ReadPascalString, but for formats that limit
+// the string to max bytes.
+//
+// If the length is greater than max, only the length byte is consumed and
+// Error() will return ErrTooLong.
+func (l *Lexer) ReadPascalStringMax(max int) string {
+	if max < 0 {
+		l.setError(ErrInvalidSize)
+		return ""
+	}
+	if v := l.peek(1); v != nil && int(v[0]) > max {
+		l.consume(1)
+		l.setError(ErrTooLong)
+		return ""
+	}
+	return string(l.readLengthPrefixed(1))
+}
+
+// WritePascalString writes s prefixed by its 8-bit length.
+//
+// If s is longer than 255 bytes, nothing is written and Error() will return
+// ErrTooLong.
+func (l *Lexer) WritePascalString(s string) {
+	l.writeLengthPrefixed(1, []byte(s))
+}
+
+// ReadUntil consumes bytes up to and including the first delim, and returns
+// a copy of the bytes before it.
+//
+// If delim is not found, all remaining bytes are consumed and returned, and
+// Error() will return io.ErrUnexpectedEOF.
+func (l *Lexer) ReadUntil(delim byte) []byte {
+	i := l.indexByte(delim, maxInt)
+	if i < 0 {
+		p := l.CopyN(l.Len())
+		l.setError(io.ErrUnexpectedEOF)
+		return p
+	}
+	p := l.CopyN(i)
+	l.consume(1)
+	return p
+}
+
+// ReadLine consumes a line terminated by '\n' and returns it without the
+// "\n" or "\r\n" line ending.
+//
+// If there is no '\n', all remaining bytes are consumed and returned, and
+// Error() will return io.ErrUnexpectedEOF.
+func (l *Lexer) ReadLine() []byte {
+	return bytes.TrimSuffix(l.ReadUntil('\n'), []byte{'\r'})
+}
+
+// ReadUTF16Z reads a NUL-terminated UTF-16 string, whose code units are in
+// the Lexer's byte order, consuming the terminating NUL code unit.
+//
+// Unpaired surrogates are decoded as U+FFFD and Error() will return
+// ErrInvalidUTF16. If there is no NUL code unit, all remaining complete code
+// units are decoded and Error() will return io.ErrUnexpectedEOF.
+func (l *Lexer) ReadUTF16Z() string {
+	var units []uint16
+	for {
+		p := l.consume(2)
+		if p == nil {
+			break
+		}
+		u := l.order.Uint16(p)
+		if u == 0 {
+			break
+		}
+		units = append(units, u)
+	}
+
+	runes := make([]rune, 0, len(units))
+	valid := true
+	for i := 0; i < len(units); i++ {
+		r := rune(units[i])
+		if utf16.IsSurrogate(r) {
+			if i+1 < len(units) {
+				r = utf16.DecodeRune(r, rune(units[i+1]))
+			} else {
+				r = unicode.ReplacementChar
+			}
+			if r == unicode.ReplacementChar {
+				valid = false
+			} else {
+				i++
+			}
+		}
+		runes = append(runes, r)
+	}
+	if !valid {
+		l.setError(ErrInvalidUTF16)
+	}
+	return string(runes)
+}
+
+// WriteUTF16Z writes s as a NUL-terminated UTF-16 string, with code units in
+// the Lexer's byte order. Invalid UTF-8 in s is written as U+FFFD.
+//
+// If an error occurred, Error() will return a non-nil error.
+func (l *Lexer) WriteUTF16Z(s string) {
+	units := utf16.Encode([]rune(s))
+	b := l.append(2 * (len(units) + 1))
+	if b == nil {
+		return
+	}
+	for i, u := range units {
+		l.order.PutUint16(b[2*i:], u)
+	}
+}
+
+// DetectBOM consumes a byte-order mark at the read position, if there is
+// one, and returns the byte order it indicates:
+//
+//   - EF BB BF (UTF-8) returns nil, true, since UTF-8 has no byte order.
+//   - FE FF (UTF-16 big endian) returns binary.BigEndian, true.
+//   - FF FE (UTF-16 little endian) returns binary.LittleEndian, true.
+//
+// Otherwise, nothing is consumed and DetectBOM returns nil, false. The
+// Lexer's byte order is not changed; to read UTF-16 in the indicated order,
+// use
+//
+//	if order, ok := l.DetectBOM(); ok && order != nil {
+//		l.SetOrder(order)
+//	}
+func (l *Lexer) DetectBOM() (binary.ByteOrder, bool) {
+	l.Has(3)
+	d := l.Data()
+	switch {
+	case bytes.HasPrefix(d, []byte{0xef, 0xbb, 0xbf}):
+		l.consume(3)
+		return nil, true
+	case bytes.HasPrefix(d, []byte{0xfe, 0xff}):
+		l.consume(2)
+		return binary.BigEndian, true
+	case bytes.HasPrefix(d, []byte{0xff, 0xfe}):
+		l.consume(2)
+		return binary.LittleEndian, true
+	}
+	return nil, false
+}
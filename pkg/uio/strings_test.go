@@ -0,0 +1,449 @@
+// Copyright 2018 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package uio
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"testing"
+)
+
+func TestReadCString(t *testing.T) {
+	for i, tt := range []struct {
+		in   []byte
+		max  int
+		want []string
+		left int
+		err  error
+	}{
+		{
+			in:   []byte("foo\x00bar\x00"),
+			max:  -1,
+			want: []string{"foo", "bar"},
+		},
+		{
+			in:   []byte("\x00\x00x"),
+			max:  -1,
+			want: []string{"", ""},
+			left: 1,
+		},
+		{
+			in:   []byte("foo\x00bar"),
+			max:  -1,
+			want: []string{"foo", "bar"},
+			err:  io.ErrUnexpectedEOF,
+		},
+		{
+			in:   []byte("foo\x00"),
+			max:  3,
+			want: []string{"foo"},
+		},
+		{
+			in:   []byte("foobar\x00"),
+			max:  3,
+			want: []string{"foo"},
+			left: 4,
+			err:  ErrTooLong,
+		},
+		{
+			in:   []byte("fo"),
+			max:  3,
+			want: []string{"fo"},
+			err:  io.ErrUnexpectedEOF,
+		},
+		{
+			in:   []byte("foo\x00bar"),
+			max:  maxInt,
+			want: []string{"foo", "bar"},
+			err:  io.ErrUnexpectedEOF,
+		},
+	} {
+		t.Run(fmt.Sprintf("Test #%02d", i), func(t *testing.T) {
+			l := NewBigEndianBuffer(tt.in)
+			for _, want := range tt.want {
+				var got string
+				if tt.max < 0 {
+					got = l.ReadCString()
+				} else {
+					got = l.ReadCStringMax(tt.max)
+				}
+				if got != want {
+					t.Errorf("ReadCString() = %q, want %q", got, want)
+				}
+			}
+			if err := l.Error(); !errors.Is(err, tt.err) {
+				t.Errorf("Error() = %v, want %v", err, tt.err)
+			}
+			if l.Len() != tt.left {
+				t.Errorf("Len() = %d, want %d", l.Len(), tt.left)
+			}
+		})
+	}
+}
+
+func TestFixedString(t *testing.T) {
+	for i, tt := range []struct {
+		s    string
+		n    int
+		pad  byte
+		want []byte
+		read string
+	}{
+		{s: "foo", n: 5, pad: 0, want: []byte("foo\x00\x00"), read: "foo"},
+		{s: "foo", n: 5, pad: ' ', want: []byte("foo  "), read: "foo  "},
+		{s: "foo", n: 3, pad: 0, want: []byte("foo"), read: "foo"},
+		{s: "", n: 2, pad: 0, want: []byte("\x00\x00"), read: ""},
+	} {
+		t.Run(fmt.Sprintf("Test #%02d", i), func(t *testing.T) {
+			l := NewBigEndianBuffer(nil)
+			l.WriteFixedString(tt.s, tt.n, tt.pad)
+			if got := l.Data(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("WriteFixedString(%q, %d, %q) = %q, want %q", tt.s, tt.n, tt.pad, got, tt.want)
+			}
+			if got := l.ReadFixedString(tt.n); got != tt.read {
+				t.Errorf("ReadFixedString(%d) = %q, want %q", tt.n, got, tt.read)
+			}
+			if err := l.Error(); err != nil {
+				t.Errorf("Error() = %v, want nil", err)
+			}
+		})
+	}
+
+	l := NewBigEndianBuffer(nil)
+	l.WriteFixedString("foobar", 3, 0)
+	if l.Len() != 0 {
+		t.Errorf("WriteFixedString(\"foobar\", 3) wrote %d bytes, want 0", l.Len())
+	}
+	if err := l.Error(); !errors.Is(err, ErrTooLong) {
+		t.Errorf("Error() = %v, want %v", err, ErrTooLong)
+	}
+
+	l = NewBigEndianBuffer([]byte("ab"))
+	if got := l.ReadFixedString(3); got != "" {
+		t.Errorf("ReadFixedString(3) on short buffer = %q, want empty", got)
+	}
+	if err := l.Error(); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("Error() = %v, want %v", err, io.ErrUnexpectedEOF)
+	}
+}
+
+func TestLengthPrefixed(t *testing.T) {
+	l := NewBigEndianBuffer(nil)
+	l.WriteLengthPrefixed8([]byte{0xaa})
+	l.WriteLengthPrefixed16([]byte{0xbb, 0xcc})
+	l.WriteLengthPrefixed32(nil)
+	l.WriteLengthPrefixedString8("foo")
+	l.WriteLengthPrefixedString16("")
+	l.WriteLengthPrefixedString32("bar")
+	want := []byte{
+		0x01, 0xaa,
+		0x00, 0x02, 0xbb, 0xcc,
+		0x00, 0x00, 0x00, 0x00,
+		0x03, 'f', 'o', 'o',
+		0x00, 0x00,
+		0x00, 0x00, 0x00, 0x03, 'b', 'a', 'r',
+	}
+	if got := l.Data(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("Data() = %#v, want %#v", got, want)
+	}
+
+	if got, want := l.ReadLengthPrefixed8(), []byte{0xaa}; !reflect.DeepEqual(got, want) {
+		t.Errorf("ReadLengthPrefixed8() = %#v, want %#v", got, want)
+	}
+	if got, want := l.ReadLengthPrefixed16(), []byte{0xbb, 0xcc}; !reflect.DeepEqual(got, want) {
+		t.Errorf("ReadLengthPrefixed16() = %#v, want %#v", got, want)
+	}
+	if got := l.ReadLengthPrefixed32(); len(got) != 0 {
+		t.Errorf("ReadLengthPrefixed32() = %#v, want empty", got)
+	}
+	if got, want := l.ReadLengthPrefixedString8(), "foo"; got != want {
+		t.Errorf("ReadLengthPrefixedString8() = %q, want %q", got, want)
+	}
+	if got, want := l.ReadLengthPrefixedString16(), ""; got != want {
+		t.Errorf("ReadLengthPrefixedString16() = %q, want %q", got, want)
+	}
+	if got, want := l.ReadLengthPrefixedString32(), "bar"; got != want {
+		t.Errorf("ReadLengthPrefixedString32() = %q, want %q", got, want)
+	}
+	if err := l.Error(); err != nil {
+		t.Errorf("Error() = %v, want nil", err)
+	}
+}
+
+func TestLengthPrefixedErrors(t *testing.T) {
+	l := NewLittleEndianBuffer(nil)
+	l.WriteLengthPrefixed8(make([]byte, 256))
+	if l.Len() != 0 {
+		t.Errorf("WriteLengthPrefixed8 of 256 bytes wrote %d bytes, want 0", l.Len())
+	}
+	if err := l.Error(); !errors.Is(err, ErrTooLong) {
+		t.Errorf("Error() = %v, want %v", err, ErrTooLong)
+	}
+
+	l = NewLittleEndianBuffer([]byte{0x05, 0x00, 'a', 'b'})
+	if got := l.ReadLengthPrefixed16(); got != nil {
+		t.Errorf("ReadLengthPrefixed16() = %#v, want nil", got)
+	}
+	if err := l.Error(); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("Error() = %v, want %v", err, io.ErrUnexpectedEOF)
+	}
+}
+
+func TestLengthPrefixedAfterError(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		in   []byte
+		read func(l *Lexer) interface{}
+		want interface{}
+	}{
+		{"ReadLengthPrefixed8", []byte{0x02, 'a', 'b'}, func(l *Lexer) interface{} { return l.ReadLengthPrefixed8() }, []byte("ab")},
+		{"ReadPascalString", []byte{0x02, 'a', 'b'}, func(l *Lexer) interface{} { return l.ReadPascalString() }, "ab"},
+		{"ReadBigIntMinimal", []byte{0x00, 0x01, 0x05}, func(l *Lexer) interface{} { return l.ReadBigIntMinimal().Int64() }, int64(5)},
+		{"ReadVarBytes", []byte{0x02, 'a', 'b'}, func(l *Lexer) interface{} { return l.ReadVarBytes() }, []byte("ab")},
+	} {
+		// An earlier, unrelated error must not desync the stream.
+		l := NewBigEndianBuffer(append(append([]byte{0x09}, tt.in...), 0xee))
+		l.ReadEnum8(1)
+		if got := tt.read(l); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s() after an error = %#v, want %#v", tt.name, got, tt.want)
+		}
+		if got := l.Read8(); got != 0xee {
+			t.Errorf("Read8() after %s() = %#x, want 0xee", tt.name, got)
+		}
+		if errs := l.Errors(); len(errs) != 1 {
+			t.Errorf("%s(): Errors() = %v, want only the earlier error", tt.name, errs)
+		}
+	}
+}
+
+func TestReadLengthPrefixedInto(t *testing.T) {
+	for i, tt := range []struct {
+		in   []byte
+		want header
+		tell int
+		err  error
+	}{
+		{
+			in:   []byte{0x03, 0x07, 0x00, 0x09, 0xff},
+			want: header{Type: 7, Len: 9},
+			tell: 4,
+		},
+		{
+			// The record is followed by a byte it does not read.
+			in:   []byte{0x04, 0x07, 0x00, 0x09, 0xff},
+			want: header{Type: 7, Len: 9},
+			tell: 5,
+			err:  ErrUnreadBytes,
+		},
+		{
+			// The record is shorter than the header it contains.
+			in:   []byte{0x02, 0x07, 0x00, 0x09},
+			want: header{Type: 7},
+			tell: 3,
+			err:  io.ErrUnexpectedEOF,
+		},
+		{
+			in:   []byte{0x05, 0x07, 0x00, 0x09},
+			tell: 1,
+			err:  io.ErrUnexpectedEOF,
+		},
+		{
+			in:  nil,
+			err: io.ErrUnexpectedEOF,
+		},
+	} {
+		t.Run(fmt.Sprintf("Test #%02d", i), func(t *testing.T) {
+			l := NewBigEndianBuffer(tt.in)
+			var h header
+			err := l.ReadLengthPrefixedInto(1, &h)
+			if !errors.Is(err, tt.err) || (tt.err == nil && err != nil) {
+				t.Errorf("ReadLengthPrefixedInto() = %v, want %v", err, tt.err)
+			}
+			if !errors.Is(l.Error(), tt.err) {
+				t.Errorf("Error() = %v, want %v", l.Error(), tt.err)
+			}
+			if h != tt.want {
+				t.Errorf("ReadLengthPrefixedInto() read %+v, want %+v", h, tt.want)
+			}
+			if got := l.Tell(); got != tt.tell {
+				t.Errorf("Tell() = %d, want %d", got, tt.tell)
+			}
+		})
+	}
+}
+
+func TestPascalString(t *testing.T) {
+	l := NewLittleEndianBuffer(nil)
+	l.WritePascalString("VOLUME1")
+	l.WritePascalString("")
+	l.WritePascalString("toolong")
+	l.WritePascalString(string(make([]byte, 256)))
+	if err := l.Error(); !errors.Is(err, ErrTooLong) {
+		t.Errorf("WritePascalString of 256 bytes: Error() = %v, want %v", err, ErrTooLong)
+	}
+	if got, want := l.Len(), 8+1+8; got != want {
+		t.Fatalf("Len() = %d, want %d", got, want)
+	}
+
+	l = NewLittleEndianBuffer(l.Data())
+	if got, want := l.ReadPascalString(), "VOLUME1"; got != want {
+		t.Errorf("ReadPascalString() = %q, want %q", got, want)
+	}
+	if got, want := l.ReadPascalStringMax(0), ""; got != want {
+		t.Errorf("ReadPascalStringMax(0) = %q, want %q", got, want)
+	}
+	if err := l.Error(); err != nil {
+		t.Errorf("Error() = %v, want nil", err)
+	}
+	if got := l.ReadPascalStringMax(6); got != "" {
+		t.Errorf("ReadPascalStringMax(6) = %q, want empty", got)
+	}
+	if err := l.Error(); !errors.Is(err, ErrTooLong) {
+		t.Errorf("Error() = %v, want %v", err, ErrTooLong)
+	}
+	if got, want := l.Len(), 7; got != want {
+		t.Errorf("Len() after ReadPascalStringMax(6) = %d, want %d", got, want)
+	}
+}
+
+func TestReadUntil(t *testing.T) {
+	l := NewBigEndianBuffer([]byte("a=b;c=d;;e"))
+	for _, want := range []string{"a=b", "c=d", ""} {
+		if got := l.ReadUntil(';'); string(got) != want {
+			t.Errorf("ReadUntil(';') = %q, want %q", got, want)
+		}
+	}
+	if err := l.Error(); err != nil {
+		t.Errorf("Error() = %v, want nil", err)
+	}
+	if got, want := l.ReadUntil(';'), "e"; string(got) != want {
+		t.Errorf("ReadUntil(';') = %q, want %q", got, want)
+	}
+	if err := l.Error(); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("Error() = %v, want %v", err, io.ErrUnexpectedEOF)
+	}
+	if l.Len() != 0 {
+		t.Errorf("Len() = %d, want 0", l.Len())
+	}
+}
+
+func TestReadLine(t *testing.T) {
+	l := NewBigEndianBuffer([]byte("GET / HTTP/1.0\r\nHost: foo\n\r\n\x01\x02"))
+	for _, want := range []string{"GET / HTTP/1.0", "Host: foo", ""} {
+		if got := l.ReadLine(); string(got) != want {
+			t.Errorf("ReadLine() = %q, want %q", got, want)
+		}
+	}
+	if err := l.Error(); err != nil {
+		t.Errorf("Error() = %v, want nil", err)
+	}
+	if got, want := l.Data(), []byte{0x01, 0x02}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Data() = %#v, want %#v", got, want)
+	}
+}
+
+func TestUTF16Z(t *testing.T) {
+	for i, tt := range []struct {
+		in   []byte
+		want string
+		left int
+		err  error
+	}{
+		{
+			// "EFI" followed by U+1F600 as a surrogate pair.
+			in:   []byte{'E', 0, 'F', 0, 'I', 0, 0x3d, 0xd8, 0x00, 0xde, 0, 0, 0xff},
+			want: "EFI\U0001f600",
+			left: 1,
+		},
+		{
+			in:   []byte{0, 0},
+			want: "",
+		},
+		{
+			// Lone high surrogate.
+			in:   []byte{'a', 0, 0x3d, 0xd8, 'b', 0, 0, 0},
+			want: "a\ufffdb",
+			err:  ErrInvalidUTF16,
+		},
+		{
+			// Lone low surrogate at the end.
+			in:   []byte{0x00, 0xde, 0, 0},
+			want: "\ufffd",
+			err:  ErrInvalidUTF16,
+		},
+		{
+			// Truncated code unit.
+			in:   []byte{'a', 0, 'b'},
+			want: "a",
+			err:  io.ErrUnexpectedEOF,
+		},
+	} {
+		t.Run(fmt.Sprintf("Test #%02d", i), func(t *testing.T) {
+			l := NewLittleEndianBuffer(tt.in)
+			if got := l.ReadUTF16Z(); got != tt.want {
+				t.Errorf("ReadUTF16Z() = %q, want %q", got, tt.want)
+			}
+			if err := l.Error(); !errors.Is(err, tt.err) {
+				t.Errorf("Error() = %v, want %v", err, tt.err)
+			}
+			if tt.err == nil && l.Len() != tt.left {
+				t.Errorf("Len() = %d, want %d", l.Len(), tt.left)
+			}
+		})
+	}
+
+	l := NewBigEndianBuffer(nil)
+	l.WriteUTF16Z("EFI\U0001f600")
+	want := []byte{0, 'E', 0, 'F', 0, 'I', 0xd8, 0x3d, 0xde, 0x00, 0, 0}
+	if got := l.Data(); !reflect.DeepEqual(got, want) {
+		t.Errorf("WriteUTF16Z() wrote %#v, want %#v", got, want)
+	}
+	if got := l.ReadUTF16Z(); got != "EFI\U0001f600" {
+		t.Errorf("ReadUTF16Z() = %q, want %q", got, "EFI\U0001f600")
+	}
+}
+
+func TestDetectBOM(t *testing.T) {
+	for i, tt := range []struct {
+		in    []byte
+		order binary.ByteOrder
+		ok    bool
+		left  int
+	}{
+		{in: []byte{0xef, 0xbb, 0xbf, 'a'}, order: nil, ok: true, left: 1},
+		{in: []byte{0xfe, 0xff, 0x00, 'a'}, order: binary.BigEndian, ok: true, left: 2},
+		{in: []byte{0xff, 0xfe, 'a', 0x00}, order: binary.LittleEndian, ok: true, left: 2},
+		{in: []byte{0xff, 0xfe}, order: binary.LittleEndian, ok: true, left: 0},
+		{in: []byte{0xef, 0xbb}, order: nil, ok: false, left: 2},
+		{in: []byte("abc"), order: nil, ok: false, left: 3},
+		{in: nil, order: nil, ok: false, left: 0},
+	} {
+		l := NewLexer(NewBuffer(tt.in), binary.BigEndian)
+		order, ok := l.DetectBOM()
+		if order != tt.order || ok != tt.ok {
+			t.Errorf("#%d: DetectBOM() = %v, %v, want %v, %v", i, order, ok, tt.order, tt.ok)
+		}
+		if l.Len() != tt.left {
+			t.Errorf("#%d: Len() = %d, want %d", i, l.Len(), tt.left)
+		}
+		if l.Order() != binary.BigEndian {
+			t.Errorf("#%d: Order() = %v, want %v", i, l.Order(), binary.BigEndian)
+		}
+		if err := l.Error(); err != nil {
+			t.Errorf("#%d: Error() = %v, want nil", i, err)
+		}
+	}
+
+	l := NewBigEndianBuffer([]byte{0xff, 0xfe, 'h', 0x00, 'i', 0x00, 0x00, 0x00})
+	if order, ok := l.DetectBOM(); ok && order != nil {
+		l.SetOrder(order)
+	}
+	if got, want := l.ReadUTF16Z(), "hi"; got != want {
+		t.Errorf("ReadUTF16Z() = %q, want %q", got, want)
+	}
+}
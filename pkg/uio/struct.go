@@ -0,0 +1,353 @@
+// Copyright 2018 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package uio
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// ErrUnsupportedType is set when a value of a type that cannot be marshaled
+// is marshaled or unmarshaled.
+var ErrUnsupportedType = errors.New("unsupported type")
+
+var (
+	marshalerType   = reflect.TypeOf((*Marshaler)(nil)).Elem()
+	unmarshalerType = reflect.TypeOf((*Unmarshaler)(nil)).Elem()
+)
+
+// UnmarshalStruct reads the exported fields of the struct pointed to by v
+// from l, in order, using l's byte order.
+//
+// Fields may be fixed-size integers, floats, bools, arrays, nested structs,
+// or types implementing Unmarshaler. Byte slices and strings need a fixed
+// size, given with a `uio:"len:N"` tag; trailing NULs are trimmed from
+// strings. Fields tagged `uio:"-"` are skipped.
+//
+// Errors are set in l, with the field name; UnmarshalStruct returns
+// l.Error().
+func UnmarshalStruct(l *Lexer, v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.Elem().Kind() != reflect.Struct {
+		l.setError(fmt.Errorf("%w: %T is not a pointer to a struct", ErrUnsupportedType, v))
+		return l.Error()
+	}
+	field := l.field
+	unmarshalValue(l, rv.Elem(), "")
+	l.field = field
+	return l.Error()
+}
+
+// MarshalStruct writes the exported fields of the struct v, or the struct
+// pointed to by v, to l, in order, using l's byte order.
+//
+// See UnmarshalStruct for the supported field types and tags. Strings are
+// padded to their size with NULs.
+//
+// Errors are set in l, with the field name; MarshalStruct returns
+// l.Error().
+func MarshalStruct(l *Lexer, v interface{}) error {
+	rv := reflect.Indirect(reflect.ValueOf(v))
+	if rv.Kind() != reflect.Struct {
+		l.setError(fmt.Errorf("%w: %T is not a struct", ErrUnsupportedType, v))
+		return l.Error()
+	}
+	field := l.field
+	marshalValue(l, rv, "")
+	l.field = field
+	return l.Error()
+}
+
+// WriteStructPadded is like MarshalStruct, but inserts zero padding between
+// fields, and after the last field, as a C compiler would for the same
+// struct on an LP64 platform such as amd64 or arm64.
+//
+// Each field is aligned to its natural alignment, relative to the start of
+// the outermost struct:
+//
+//   - bools and 8-bit integers are aligned to 1 byte,
+//   - 16-, 32- and 64-bit integers and floats are aligned to their size,
+//   - arrays are aligned like their element type,
+//   - strings and byte slices are aligned to 1 byte,
+//   - types implementing Marshaler are aligned to 1 byte,
+//   - and structs are aligned to the largest alignment of their fields.
+//
+// A struct is padded at the end to a multiple of its alignment. A
+// `uio:"align:N"` tag overrides a field's alignment, and may be combined
+// with a len tag as `uio:"len:N,align:M"`.
+//
+// If an error occurred, Error() will return a non-nil error.
+func (l *Lexer) WriteStructPadded(v interface{}) {
+	rv := reflect.Indirect(reflect.ValueOf(v))
+	if rv.Kind() != reflect.Struct {
+		l.setError(fmt.Errorf("%w: %T is not a struct", ErrUnsupportedType, v))
+		return
+	}
+	field := l.field
+	marshalPadded(l, l.writeEnd(), rv, "")
+	l.field = field
+}
+
+// tagOption returns the value of the key:value option in a comma-separated
+// uio tag.
+func tagOption(tag, key string) (string, bool) {
+	for _, opt := range strings.Split(tag, ",") {
+		if strings.HasPrefix(opt, key+":") {
+			return strings.TrimPrefix(opt, key+":"), true
+		}
+	}
+	return "", false
+}
+
+// tagLen returns the N in a `uio:"len:N"` tag, or -1 if there is none.
+func tagLen(l *Lexer, tag string) int {
+	opt, ok := tagOption(tag, "len")
+	if !ok {
+		return -1
+	}
+	n, err := strconv.Atoi(opt)
+	if err != nil || n < 0 {
+		l.setError(fmt.Errorf("%w: invalid uio tag %q", ErrInvalidSize, tag))
+		return -1
+	}
+	return n
+}
+
+// alignOf returns the C alignment of a value of type t with the given tag,
+// as described by WriteStructPadded.
+func alignOf(l *Lexer, t reflect.Type, tag string) int {
+	if opt, ok := tagOption(tag, "align"); ok {
+		n, err := strconv.Atoi(opt)
+		if err != nil || n < 1 || n&(n-1) != 0 {
+			l.setError(fmt.Errorf("%w: invalid uio tag %q", ErrInvalidSize, tag))
+			return 1
+		}
+		return n
+	}
+	if t.Implements(marshalerType) || reflect.PtrTo(t).Implements(marshalerType) {
+		return 1
+	}
+
+	switch t.Kind() {
+	case reflect.Uint16, reflect.Int16:
+		return 2
+	case reflect.Uint32, reflect.Int32, reflect.Float32:
+		return 4
+	case reflect.Uint64, reflect.Int64, reflect.Float64:
+		return 8
+	case reflect.Array:
+		return alignOf(l, t.Elem(), "")
+	case reflect.Struct:
+		a := 1
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			tag := f.Tag.Get("uio")
+			if f.PkgPath != "" || tag == "-" {
+				continue
+			}
+			if fa := alignOf(l, f.Type, tag); fa > a {
+				a = fa
+			}
+		}
+		return a
+	}
+	return 1
+}
+
+// padTo writes zero bytes until the length written since start is a
+// multiple of n.
+func padTo(l *Lexer, start, n int) {
+	if r := (l.writeEnd() - start) % n; r != 0 {
+		l.WriteZeroes(n - r)
+	}
+}
+
+// marshalPadded is like marshalValue, but pads struct fields to their C
+// alignment relative to start.
+func marshalPadded(l *Lexer, start int, v reflect.Value, tag string) {
+	if alignOf(l, v.Type(), "") == 1 {
+		marshalValue(l, v, tag)
+		return
+	}
+
+	switch v.Kind() {
+	case reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			marshalPadded(l, start, v.Index(i), "")
+		}
+
+	case reflect.Struct:
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			tag := f.Tag.Get("uio")
+			if f.PkgPath != "" || tag == "-" {
+				continue
+			}
+			l.field = f.Name
+			padTo(l, start, alignOf(l, f.Type, tag))
+			marshalPadded(l, start, v.Field(i), tag)
+		}
+		padTo(l, start, alignOf(l, t, ""))
+
+	default:
+		marshalValue(l, v, tag)
+	}
+}
+
+func unmarshalValue(l *Lexer, v reflect.Value, tag string) {
+	if v.CanAddr() && v.Addr().Type().Implements(unmarshalerType) {
+		if err := v.Addr().Interface().(Unmarshaler).Unmarshal(l); err != nil {
+			l.setError(err)
+		}
+		return
+	}
+
+	switch v.Kind() {
+	case reflect.Bool:
+		v.SetBool(l.ReadBool())
+	case reflect.Uint8:
+		v.SetUint(uint64(l.Read8()))
+	case reflect.Uint16:
+		v.SetUint(uint64(l.Read16()))
+	case reflect.Uint32:
+		v.SetUint(uint64(l.Read32()))
+	case reflect.Uint64:
+		v.SetUint(l.Read64())
+	case reflect.Int8:
+		v.SetInt(int64(l.Read8s()))
+	case reflect.Int16:
+		v.SetInt(int64(l.Read16s()))
+	case reflect.Int32:
+		v.SetInt(int64(l.Read32s()))
+	case reflect.Int64:
+		v.SetInt(l.Read64s())
+	case reflect.Float32:
+		v.SetFloat(float64(l.ReadFloat32()))
+	case reflect.Float64:
+		v.SetFloat(l.ReadFloat64())
+
+	case reflect.Array:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			if b := l.CopyN(v.Len()); b != nil {
+				reflect.Copy(v, reflect.ValueOf(b))
+			}
+			return
+		}
+		for i := 0; i < v.Len(); i++ {
+			unmarshalValue(l, v.Index(i), "")
+		}
+
+	case reflect.Slice:
+		n := tagLen(l, tag)
+		if n < 0 || v.Type().Elem().Kind() != reflect.Uint8 {
+			l.setError(fmt.Errorf("%w: %v", ErrUnsupportedType, v.Type()))
+			return
+		}
+		v.SetBytes(l.CopyN(n))
+
+	case reflect.String:
+		n := tagLen(l, tag)
+		if n < 0 {
+			l.setError(fmt.Errorf("%w: %v", ErrUnsupportedType, v.Type()))
+			return
+		}
+		v.SetString(l.ReadFixedString(n))
+
+	case reflect.Struct:
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			tag := f.Tag.Get("uio")
+			if f.PkgPath != "" || tag == "-" {
+				continue
+			}
+			l.field = f.Name
+			unmarshalValue(l, v.Field(i), tag)
+		}
+
+	default:
+		l.setError(fmt.Errorf("%w: %v", ErrUnsupportedType, v.Type()))
+	}
+}
+
+func marshalValue(l *Lexer, v reflect.Value, tag string) {
+	if v.Type().Implements(marshalerType) {
+		v.Interface().(Marshaler).Marshal(l)
+		return
+	}
+	if v.CanAddr() && v.Addr().Type().Implements(marshalerType) {
+		v.Addr().Interface().(Marshaler).Marshal(l)
+		return
+	}
+
+	switch v.Kind() {
+	case reflect.Bool:
+		l.WriteBool(v.Bool())
+	case reflect.Uint8:
+		l.Write8(uint8(v.Uint()))
+	case reflect.Uint16:
+		l.Write16(uint16(v.Uint()))
+	case reflect.Uint32:
+		l.Write32(uint32(v.Uint()))
+	case reflect.Uint64:
+		l.Write64(v.Uint())
+	case reflect.Int8:
+		l.Write8s(int8(v.Int()))
+	case reflect.Int16:
+		l.Write16s(int16(v.Int()))
+	case reflect.Int32:
+		l.Write32s(int32(v.Int()))
+	case reflect.Int64:
+		l.Write64s(v.Int())
+	case reflect.Float32:
+		l.WriteFloat32(float32(v.Float()))
+	case reflect.Float64:
+		l.WriteFloat64(v.Float())
+
+	case reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			marshalValue(l, v.Index(i), "")
+		}
+
+	case reflect.Slice:
+		n := tagLen(l, tag)
+		if n < 0 || v.Type().Elem().Kind() != reflect.Uint8 {
+			l.setError(fmt.Errorf("%w: %v", ErrUnsupportedType, v.Type()))
+			return
+		}
+		if v.Len() != n {
+			l.setError(ErrInvalidSize)
+			return
+		}
+		l.WriteBytes(v.Bytes())
+
+	case reflect.String:
+		n := tagLen(l, tag)
+		if n < 0 {
+			l.setError(fmt.Errorf("%w: %v", ErrUnsupportedType, v.Type()))
+			return
+		}
+		l.WriteFixedString(v.String(), n, 0)
+
+	case reflect.Struct:
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			tag := f.Tag.Get("uio")
+			if f.PkgPath != "" || tag == "-" {
+				continue
+			}
+			l.field = f.Name
+			marshalValue(l, v.Field(i), tag)
+		}
+
+	default:
+		l.setError(fmt.Errorf("%w: %v", ErrUnsupportedType, v.Type()))
+	}
+}
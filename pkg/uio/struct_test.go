@@ -0,0 +1,207 @@
+// Copyright 2018 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package uio
+
+import (
+	"errors"
+	"io"
+	"reflect"
+	"testing"
+)
+
+type structInner struct {
+	A uint16
+	B int8
+}
+
+type structTest struct {
+	Magic   [4]byte
+	Version uint8
+	Flags   uint16
+	Offset  int32
+	Inner   structInner
+	Name    string `uio:"len:6"`
+	Raw     []byte `uio:"len:2"`
+	Skipped uint32 `uio:"-"`
+	Ok      bool
+	Ratio   float32
+	Header  header
+	Words   [2]uint16
+	private uint64
+}
+
+func TestStructRoundTrip(t *testing.T) {
+	want := []byte{
+		'U', 'R', 'O', 'T',
+		0x02,
+		0x80, 0x01,
+		0xff, 0xff, 0xff, 0xfe,
+		0x12, 0x34, 0xfd,
+		'f', 'o', 'o', 0, 0, 0,
+		0xaa, 0xbb,
+		0x01,
+		0x3f, 0x80, 0x00, 0x00,
+		0x07, 0x00, 0x09,
+		0x00, 0x01, 0x00, 0x02,
+	}
+	s := structTest{
+		Magic:   [4]byte{'U', 'R', 'O', 'T'},
+		Version: 2,
+		Flags:   0x8001,
+		Offset:  -2,
+		Inner:   structInner{A: 0x1234, B: -3},
+		Name:    "foo",
+		Raw:     []byte{0xaa, 0xbb},
+		Skipped: 42,
+		Ok:      true,
+		Ratio:   1.0,
+		Header:  header{Type: 7, Len: 9},
+		Words:   [2]uint16{1, 2},
+		private: 42,
+	}
+
+	l := NewBigEndianBuffer(nil)
+	if err := MarshalStruct(l, s); err != nil {
+		t.Fatalf("MarshalStruct() = %v", err)
+	}
+	if got := l.Data(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("MarshalStruct() = %#v, want %#v", got, want)
+	}
+
+	var got structTest
+	if err := UnmarshalStruct(l, &got); err != nil {
+		t.Fatalf("UnmarshalStruct() = %v", err)
+	}
+	s.Skipped, s.private = 0, 0
+	if !reflect.DeepEqual(got, s) {
+		t.Errorf("UnmarshalStruct() = %+v, want %+v", got, s)
+	}
+	if l.Len() != 0 {
+		t.Errorf("Len() after UnmarshalStruct = %d, want 0", l.Len())
+	}
+}
+
+func TestUnmarshalStructErrors(t *testing.T) {
+	var s structInner
+	l := NewLittleEndianBuffer([]byte{0x01, 0x02})
+	err := UnmarshalStruct(l, &s)
+	if !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("UnmarshalStruct() = %v, want %v", err, io.ErrUnexpectedEOF)
+	}
+	var ferr *FieldError
+	if !errors.As(err, &ferr) || ferr.Field != "B" {
+		t.Errorf("UnmarshalStruct() = %v, want error in field B", err)
+	}
+
+	for _, v := range []interface{}{
+		s,
+		&struct{ S []byte }{},
+		&struct{ S string }{},
+		&struct{ M map[int]int }{},
+		&struct{ I int }{},
+	} {
+		l := NewLittleEndianBuffer(make([]byte, 16))
+		if err := UnmarshalStruct(l, v); !errors.Is(err, ErrUnsupportedType) {
+			t.Errorf("UnmarshalStruct(%T) = %v, want %v", v, err, ErrUnsupportedType)
+		}
+	}
+}
+
+func TestMarshalStructErrors(t *testing.T) {
+	for _, v := range []interface{}{
+		3,
+		struct{ I int }{},
+		struct{ S []uint16 }{},
+	} {
+		l := NewLittleEndianBuffer(nil)
+		if err := MarshalStruct(l, v); !errors.Is(err, ErrUnsupportedType) {
+			t.Errorf("MarshalStruct(%T) = %v, want %v", v, err, ErrUnsupportedType)
+		}
+	}
+
+	l := NewLittleEndianBuffer(nil)
+	v := struct {
+		S []byte `uio:"len:3"`
+	}{S: []byte{1}}
+	if err := MarshalStruct(l, v); !errors.Is(err, ErrInvalidSize) {
+		t.Errorf("MarshalStruct() with a short slice = %v, want %v", err, ErrInvalidSize)
+	}
+}
+
+type paddedInner struct {
+	A uint16
+	B uint8
+}
+
+type paddedTest struct {
+	Tag    uint8
+	Inner  paddedInner
+	Value  uint64
+	Name   string `uio:"len:3"`
+	Pairs  [2]paddedInner
+	Header header
+	Kind   uint8  `uio:"align:4"`
+	Skip   uint64 `uio:"-"`
+	Count  uint32
+}
+
+func TestWriteStructPadded(t *testing.T) {
+	want := []byte{
+		0x01, 0,
+		0x02, 0x00, 0x03, 0,
+		0, 0,
+		0x04, 0, 0, 0, 0, 0, 0, 0,
+		'a', 'b', 0,
+		0,
+		0x05, 0x00, 0x06, 0,
+		0x07, 0x00, 0x08, 0,
+		0x09, 0x0a, 0x00,
+		0,
+		0x0b, 0, 0, 0,
+		0x0c, 0, 0, 0,
+	}
+	s := paddedTest{
+		Tag:    1,
+		Inner:  paddedInner{A: 2, B: 3},
+		Value:  4,
+		Name:   "ab",
+		Pairs:  [2]paddedInner{{A: 5, B: 6}, {A: 7, B: 8}},
+		Header: header{Type: 9, Len: 10},
+		Kind:   11,
+		Skip:   42,
+		Count:  12,
+	}
+
+	l := NewLittleEndianBuffer([]byte{0xff})
+	l.Read8()
+	l.WriteStructPadded(&s)
+	if err := l.Error(); err != nil {
+		t.Fatalf("WriteStructPadded() = %v", err)
+	}
+	if got := l.Data(); !reflect.DeepEqual(got, want) {
+		t.Errorf("WriteStructPadded() = %#v, want %#v", got, want)
+	}
+}
+
+func TestWriteStructPaddedErrors(t *testing.T) {
+	for _, v := range []interface{}{
+		3,
+		struct{ I int }{},
+	} {
+		l := NewLittleEndianBuffer(nil)
+		if l.WriteStructPadded(v); !errors.Is(l.Error(), ErrUnsupportedType) {
+			t.Errorf("WriteStructPadded(%T) = %v, want %v", v, l.Error(), ErrUnsupportedType)
+		}
+	}
+
+	l := NewLittleEndianBuffer(nil)
+	l.WriteStructPadded(struct {
+		A uint8
+		B uint16 `uio:"align:3"`
+	}{})
+	if !errors.Is(l.Error(), ErrInvalidSize) {
+		t.Errorf("WriteStructPadded() with align:3 = %v, want %v", l.Error(), ErrInvalidSize)
+	}
+}
@@ -0,0 +1,55 @@
+// Copyright 2018 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package uio
+
+import (
+	"hash"
+)
+
+// Tap feeds every byte subsequently consumed from or appended to l into h,
+// until Untap is called, e.g. to compute an Adler-32, MD5 or SHA-256 digest
+// over exactly the bytes of a record as it is parsed or marshaled:
+//
+//	h := adler32.New()
+//	l.Tap(h)
+//	r.Marshal(l)
+//	l.Untap()
+//	l.Write32(h.Sum32())
+//
+// Since appended bytes may be filled in after they are appended, they are
+// only fed to h on the next write or on Untap, and h should not be used
+// before then. Later in-place changes to bytes already fed to h, e.g. by
+// PlaceLength16, are not reflected in h, and bytes read again after Rewind
+// are fed again. A counting Lexer only feeds h the bytes it consumes.
+//
+// A previous tap is untapped first.
+func (l *Lexer) Tap(h hash.Hash) {
+	l.Untap()
+	l.tap = h
+	l.tapped = l.size()
+}
+
+// Untap feeds any pending appended bytes to the hash passed to Tap and stops
+// feeding it.
+func (l *Lexer) Untap() {
+	if l.tap == nil {
+		return
+	}
+	l.flushTap()
+	l.tap = nil
+}
+
+// flushTap feeds the bytes appended since the last flush to the tap.
+func (l *Lexer) flushTap() {
+	if l.counting {
+		return
+	}
+	if l.tapped > len(l.data) {
+		// The Buffer was truncated or rolled back.
+		l.tapped = len(l.data)
+	}
+	l.tap.Write(l.data[l.tapped:])
+	l.tapped = len(l.data)
+}
@@ -0,0 +1,71 @@
+// Copyright 2018 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package uio
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"hash/adler32"
+	"reflect"
+	"testing"
+)
+
+func TestTapRead(t *testing.T) {
+	data := []byte("Wikipedia")
+	l := NewBigEndianBuffer(append([]byte{0xff}, data...))
+	l.Read8()
+
+	h := adler32.New()
+	l.Tap(h)
+	l.Read16()
+	l.CopyN(3)
+	l.ReadBytes(make([]byte, 4))
+	l.Untap()
+	if err := l.Error(); err != nil {
+		t.Fatalf("Error() = %v", err)
+	}
+	// Adler-32 of "Wikipedia", from the Wikipedia article on Adler-32.
+	if got, want := h.Sum32(), uint32(0x11e60398); got != want {
+		t.Errorf("Adler-32 = %#x, want %#x", got, want)
+	}
+}
+
+func TestTapWrite(t *testing.T) {
+	l := NewLittleEndianBuffer(nil)
+	l.Write8(0xff)
+
+	h := sha256.New()
+	l.Tap(h)
+	l.Write32(0xdeadbeef)
+	cp := l.Checkpoint()
+	l.WriteBytes([]byte("discarded"))
+	l.Rollback(cp)
+	l.WriteString("hello")
+	l.Untap()
+	l.WriteBytes(h.Sum(nil))
+	if err := l.Error(); err != nil {
+		t.Fatalf("Error() = %v", err)
+	}
+
+	want := sha256.Sum256(append([]byte{0xef, 0xbe, 0xad, 0xde}, "hello"...))
+	if got := l.Data()[10:]; !reflect.DeepEqual(got, want[:]) {
+		t.Errorf("SHA-256 = %x, want %x", got, want)
+	}
+}
+
+func TestTapClone(t *testing.T) {
+	l := NewLexer(NewBuffer([]byte{1, 2, 3, 4}), binary.BigEndian)
+	h := adler32.New()
+	l.Tap(h)
+	c := l.Clone()
+	c.Read32()
+	l.Read16()
+	l.Untap()
+	l.Read16()
+
+	if got, want := h.Sum32(), adler32.Checksum([]byte{1, 2}); got != want {
+		t.Errorf("Adler-32 = %#x, want %#x", got, want)
+	}
+}
@@ -0,0 +1,147 @@
+// Copyright 2018 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package uio
+
+import (
+	"math"
+	"time"
+)
+
+// FileTimeEpoch is the epoch of Windows FILETIME timestamps, which count
+// 100ns intervals.
+var FileTimeEpoch = time.Date(1601, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+// NTPEpoch is the epoch of NTP timestamps.
+var NTPEpoch = time.Date(1900, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+// ReadUnixTime32 reads an unsigned 32-bit number of seconds since the Unix
+// epoch.
+//
+// If an error occurred, Error() will return a non-nil error.
+func (l *Lexer) ReadUnixTime32() time.Time {
+	return time.Unix(int64(l.Read32()), 0).UTC()
+}
+
+// ReadUnixTime64 reads a signed 64-bit number of seconds since the Unix
+// epoch.
+//
+// If an error occurred, Error() will return a non-nil error.
+func (l *Lexer) ReadUnixTime64() time.Time {
+	return time.Unix(int64(l.Read64()), 0).UTC()
+}
+
+// WriteUnixTime32 writes t as an unsigned 32-bit number of seconds since the
+// Unix epoch, discarding fractions of a second.
+//
+// If t is before 1970 or after 2106, nothing is written and Error() will
+// return ErrOverflow.
+func (l *Lexer) WriteUnixTime32(t time.Time) {
+	s := t.Unix()
+	if s < 0 || s > math.MaxUint32 {
+		l.setError(ErrOverflow)
+		return
+	}
+	l.Write32(uint32(s))
+}
+
+// WriteUnixTime64 writes t as a signed 64-bit number of seconds since the
+// Unix epoch, discarding fractions of a second.
+//
+// If an error occurred, Error() will return a non-nil error.
+func (l *Lexer) WriteUnixTime64(t time.Time) {
+	l.Write64(uint64(t.Unix()))
+}
+
+// maxUnixSeconds is the latest Unix time, in seconds, that a time.Time can
+// represent: its zero value is 62135596800 seconds before the Unix epoch.
+const maxUnixSeconds = math.MaxInt64 - 62135596800
+
+// epochUnit checks that unit either divides or is a multiple of a second,
+// and returns the number of units per second or seconds per unit.
+func epochUnit(unit time.Duration) (perSecond, seconds uint64, ok bool) {
+	switch {
+	case unit <= 0:
+		return 0, 0, false
+	case time.Second%unit == 0:
+		return uint64(time.Second / unit), 0, true
+	case unit%time.Second == 0:
+		return 0, uint64(unit / time.Second), true
+	}
+	return 0, 0, false
+}
+
+// ReadEpochTime reads an unsigned 64-bit count of unit intervals since
+// epoch. A Windows FILETIME, for example, is read with
+//
+//	l.ReadEpochTime(100*time.Nanosecond, uio.FileTimeEpoch)
+//
+// Counts too large for a time.Time saturate to the latest time it can
+// represent rather than wrap around.
+//
+// If unit neither divides nor is a multiple of a second, Error() will
+// return ErrInvalidSize.
+func (l *Lexer) ReadEpochTime(unit time.Duration, epoch time.Time) time.Time {
+	perSecond, seconds, ok := epochUnit(unit)
+	if !ok {
+		l.setError(ErrInvalidSize)
+		return time.Time{}
+	}
+	v := l.Read64()
+
+	var secs, nsec uint64
+	if perSecond != 0 {
+		secs, nsec = v/perSecond, v%perSecond*uint64(unit)
+	} else if v > math.MaxUint64/seconds {
+		secs = math.MaxUint64
+	} else {
+		secs = v * seconds
+	}
+	base := epoch.Unix()
+	limit := uint64(maxUnixSeconds)
+	if base > 0 {
+		limit -= uint64(base)
+	}
+	if secs > limit {
+		secs = limit
+	}
+	return time.Unix(base+int64(secs), int64(epoch.Nanosecond())+int64(nsec)).In(epoch.Location())
+}
+
+// WriteEpochTime writes t as an unsigned 64-bit count of unit intervals
+// since epoch, discarding any remainder. See ReadEpochTime.
+//
+// If unit neither divides nor is a multiple of a second, nothing is written
+// and Error() will return ErrInvalidSize. If t is before epoch or too far
+// after it, nothing is written and Error() will return ErrOverflow.
+func (l *Lexer) WriteEpochTime(t time.Time, unit time.Duration, epoch time.Time) {
+	perSecond, seconds, ok := epochUnit(unit)
+	if !ok {
+		l.setError(ErrInvalidSize)
+		return
+	}
+	secs := t.Unix() - epoch.Unix()
+	nsec := int64(t.Nanosecond() - epoch.Nanosecond())
+	if nsec < 0 {
+		secs--
+		nsec += int64(time.Second)
+	}
+	if secs < 0 {
+		l.setError(ErrOverflow)
+		return
+	}
+
+	var v uint64
+	if perSecond != 0 {
+		frac := uint64(nsec) / uint64(unit)
+		if uint64(secs) > (math.MaxUint64-frac)/perSecond {
+			l.setError(ErrOverflow)
+			return
+		}
+		v = uint64(secs)*perSecond + frac
+	} else {
+		v = uint64(secs) / seconds
+	}
+	l.Write64(v)
+}
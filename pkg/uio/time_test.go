@@ -0,0 +1,112 @@
+// Copyright 2018 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package uio
+
+import (
+	"errors"
+	"math"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestUnixTime(t *testing.T) {
+	ts := time.Date(2018, time.March, 1, 12, 30, 0, 0, time.UTC)
+
+	l := NewBigEndianBuffer(nil)
+	l.WriteUnixTime32(ts)
+	l.WriteUnixTime64(time.Date(1900, time.January, 1, 0, 0, 0, 0, time.UTC))
+	if err := l.Error(); err != nil {
+		t.Fatalf("Error() = %v, want nil", err)
+	}
+	if got, want := l.Data()[:4], []byte{0x5a, 0x97, 0xf2, 0x48}; !reflect.DeepEqual(got, want) {
+		t.Errorf("WriteUnixTime32() wrote %#v, want %#v", got, want)
+	}
+
+	if got := l.ReadUnixTime32(); !got.Equal(ts) {
+		t.Errorf("ReadUnixTime32() = %v, want %v", got, ts)
+	}
+	if got, want := l.ReadUnixTime64(), time.Date(1900, time.January, 1, 0, 0, 0, 0, time.UTC); !got.Equal(want) {
+		t.Errorf("ReadUnixTime64() = %v, want %v", got, want)
+	}
+
+	l.WriteUnixTime32(time.Date(1969, time.December, 31, 0, 0, 0, 0, time.UTC))
+	if err := l.Error(); !errors.Is(err, ErrOverflow) {
+		t.Errorf("WriteUnixTime32(1969): Error() = %v, want %v", err, ErrOverflow)
+	}
+	if l.Len() != 0 {
+		t.Errorf("WriteUnixTime32(1969) wrote %d bytes, want 0", l.Len())
+	}
+}
+
+func TestEpochTime(t *testing.T) {
+	for i, tt := range []struct {
+		unit  time.Duration
+		epoch time.Time
+		in    uint64
+		want  time.Time
+	}{
+		{
+			// The Unix epoch as a FILETIME.
+			unit:  100 * time.Nanosecond,
+			epoch: FileTimeEpoch,
+			in:    116444736000000000,
+			want:  time.Unix(0, 0),
+		},
+		{
+			unit:  100 * time.Nanosecond,
+			epoch: FileTimeEpoch,
+			in:    131643810051234567,
+			want:  time.Date(2018, time.March, 1, 12, 30, 5, 123456700, time.UTC),
+		},
+		{
+			unit:  time.Second,
+			epoch: NTPEpoch,
+			in:    3728896200,
+			want:  time.Date(2018, time.March, 1, 12, 30, 0, 0, time.UTC),
+		},
+		{
+			unit:  24 * time.Hour,
+			epoch: time.Unix(0, 0),
+			in:    17591,
+			want:  time.Date(2018, time.March, 1, 0, 0, 0, 0, time.UTC),
+		},
+	} {
+		l := NewLittleEndianBuffer(nil)
+		l.Write64(tt.in)
+		if got := l.ReadEpochTime(tt.unit, tt.epoch); !got.Equal(tt.want) {
+			t.Errorf("#%d: ReadEpochTime(%v, %v) = %v, want %v", i, tt.unit, tt.epoch, got, tt.want)
+		}
+		l.WriteEpochTime(tt.want, tt.unit, tt.epoch)
+		if got := l.Read64(); got != tt.in {
+			t.Errorf("#%d: WriteEpochTime(%v) wrote %d, want %d", i, tt.want, got, tt.in)
+		}
+		if err := l.Error(); err != nil {
+			t.Errorf("#%d: Error() = %v, want nil", i, err)
+		}
+	}
+}
+
+func TestEpochTimeErrors(t *testing.T) {
+	l := NewLittleEndianBuffer(nil)
+	l.Write64(math.MaxUint64)
+	if got := l.ReadEpochTime(24*time.Hour, FileTimeEpoch); got.Before(FileTimeEpoch) {
+		t.Errorf("ReadEpochTime(MaxUint64) = %v, want a time after the epoch", got)
+	}
+
+	l.WriteEpochTime(time.Unix(0, 0), 100*time.Nanosecond, time.Unix(1, 0))
+	if err := l.Error(); !errors.Is(err, ErrOverflow) {
+		t.Errorf("WriteEpochTime before epoch: Error() = %v, want %v", err, ErrOverflow)
+	}
+
+	l = NewLittleEndianBuffer(nil)
+	l.WriteEpochTime(time.Now(), 3*time.Second/2, time.Unix(0, 0))
+	if err := l.Error(); !errors.Is(err, ErrInvalidSize) {
+		t.Errorf("WriteEpochTime with a 1.5s unit: Error() = %v, want %v", err, ErrInvalidSize)
+	}
+	if l.Len() != 0 {
+		t.Errorf("WriteEpochTime with a 1.5s unit wrote %d bytes, want 0", l.Len())
+	}
+}
@@ -0,0 +1,178 @@
+// Copyright 2018 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package uio
+
+import (
+	"bytes"
+	"io"
+	"math"
+)
+
+// TLV reads one type-length-value record, with a typeWidth-byte type and a
+// lenWidth-byte length in the Lexer's byte order, and returns its type and a
+// Lexer bounded to its value, as returned by Limit. Calling it in a loop
+// walks a stream of records:
+//
+//	for typ, v, ok := l.TLV(1, 1); ok; typ, v, ok = l.TLV(1, 1) {
+//		...
+//	}
+//	if err := l.Error(); err != nil {
+//		...
+//	}
+//
+// TLV returns false, and sets no error, once no bytes are left. Formats with
+// options that have no length, like DHCP's pad and end options, must check
+// for those with Peek8 first.
+//
+// If either width is not in 1..8, Error() will return ErrInvalidSize. If the
+// header or the value is cut short, Error() will return
+// io.ErrUnexpectedEOF, and if the value is larger than the limit set by
+// SetMaxAlloc, ErrTooLong. In all those cases TLV returns false.
+func (l *Lexer) TLV(typeWidth, lenWidth int) (uint64, *Lexer, bool) {
+	if typeWidth < 1 || typeWidth > 8 || lenWidth < 1 || lenWidth > 8 {
+		l.setError(ErrInvalidSize)
+		return 0, nil, false
+	}
+	if !l.Has(1) {
+		return 0, nil, false
+	}
+	if !l.Has(typeWidth + lenWidth) {
+		l.setError(io.ErrUnexpectedEOF)
+		return 0, nil, false
+	}
+	typ := l.ReadUintN(typeWidth)
+	n := l.ReadUintN(lenWidth)
+	if !l.checkAlloc64(n) {
+		return 0, nil, false
+	}
+	if n > uint64(maxInt) || !l.Has(int(n)) {
+		l.setError(io.ErrUnexpectedEOF)
+		return 0, nil, false
+	}
+	return typ, l.Limit(int(n)), true
+}
+
+// PadTLV appends one-byte padType options until the total length of the
+// Buffer is a multiple of 4, for TLV formats whose options area must end on
+// a 32-bit boundary and whose pad option has no length byte, such as DHCP
+// (pad option 0) and TCP or IPv4 (NOP option 1).
+//
+// For other boundaries, or to pad with plain bytes, use AlignWrite.
+//
+// If an error occurred, Error() will return a non-nil error.
+func (l *Lexer) PadTLV(padType byte) {
+	l.AlignWrite(4, padType)
+}
+
+// checkTLVHeader returns true if a TLV header with the given widths can hold
+// typ, and sets the error otherwise.
+func (l *Lexer) checkTLVHeader(typeWidth, lenWidth int, typ uint64) bool {
+	if typeWidth < 1 || typeWidth > 8 || lenWidth < 1 || lenWidth > 8 {
+		l.setError(ErrInvalidSize)
+		return false
+	}
+	if typeWidth < 8 && typ>>(8*uint(typeWidth)) != 0 {
+		l.setError(ErrOverflow)
+		return false
+	}
+	return true
+}
+
+// WriteTLV writes a type-length-value record as read by TLV: typ in
+// typeWidth bytes, the length of value in lenWidth bytes, then value.
+//
+// If either width is not in 1..8, nothing is written and Error() will return
+// ErrInvalidSize. If typ does not fit in typeWidth bytes, Error() will
+// return ErrOverflow, and if value is too long for lenWidth bytes,
+// ErrTooLong.
+func (l *Lexer) WriteTLV(typeWidth, lenWidth int, typ uint64, value []byte) {
+	if !l.checkTLVHeader(typeWidth, lenWidth, typ) {
+		return
+	}
+	if lenWidth < 8 && uint64(len(value))>>(8*uint(lenWidth)) != 0 {
+		l.setError(ErrTooLong)
+		return
+	}
+	l.WriteUintN(typeWidth, typ)
+	l.WriteUintN(lenWidth, uint64(len(value)))
+	l.WriteBytes(value)
+}
+
+// WriteTLVFunc is like WriteTLV, but the value is whatever body writes to
+// l, and its length is filled in afterwards, e.g. for nested records:
+//
+//	l.WriteTLVFunc(1, 1, optVendor, func(l *Lexer) {
+//		l.WriteTLV(1, 1, subOptID, id)
+//		l.WriteTLV(1, 1, subOptName, name)
+//	})
+//
+// If what body wrote is too long for lenWidth bytes, the whole record is
+// discarded and Error() will return ErrTooLong. Errors set by body are kept.
+func (l *Lexer) WriteTLVFunc(typeWidth, lenWidth int, typ uint64, body func(*Lexer)) {
+	if !l.checkTLVHeader(typeWidth, lenWidth, typ) {
+		return
+	}
+	cp := l.Checkpoint()
+	l.WriteUintN(typeWidth, typ)
+	start := l.WriteMark()
+	l.WriteZeroes(lenWidth)
+	body(l)
+	if n := uint64(l.WriteMark() - start - lenWidth); lenWidth < 8 && n>>(8*uint(lenWidth)) != 0 {
+		l.Rollback(cp)
+		l.setError(ErrTooLong)
+		return
+	}
+	l.placeLength(start, lenWidth)
+}
+
+// ReadOptionMap reads TLV options, with a codeWidth-byte code and a
+// lenWidth-byte length, until an option with code endMarker or the end of
+// the Buffer, and returns a copy of each value by code. The end marker has
+// no length, as in DHCP, and is consumed. Options whose code is in skip
+// have no length either, and are consumed and left out of the map, e.g. for
+// DHCP (RFC 2132) options:
+//
+//	opts := l.ReadOptionMap(1, 1, 255, 0)
+//
+// If a code appears more than once, its values are concatenated in order,
+// as DHCP does for long options (RFC 3396).
+//
+// If either width is not in 1..8, Error() will return ErrInvalidSize, and
+// if a code does not fit in 8 bits, ErrOverflow. If an error occurred,
+// ReadOptionMap returns the options read before it.
+func (l *Lexer) ReadOptionMap(codeWidth, lenWidth int, endMarker uint8, skip ...uint8) map[uint8][]byte {
+	opts := make(map[uint8][]byte)
+	if codeWidth < 1 || codeWidth > 8 || lenWidth < 1 || lenWidth > 8 {
+		l.setError(ErrInvalidSize)
+		return opts
+	}
+	for l.Has(1) {
+		errs := len(l.errs)
+		code := l.ReadUintN(codeWidth)
+		if len(l.errs) != errs || code == uint64(endMarker) {
+			break
+		}
+		if code > math.MaxUint8 {
+			l.setError(ErrOverflow)
+			break
+		}
+		if bytes.IndexByte(skip, uint8(code)) >= 0 {
+			continue
+		}
+		n := l.ReadUintN(lenWidth)
+		if len(l.errs) != errs {
+			break
+		}
+		v := l.readPrefixed(n)
+		if len(l.errs) != errs {
+			break
+		}
+		if old, ok := opts[uint8(code)]; ok {
+			v = append(old, v...)
+		}
+		opts[uint8(code)] = v
+	}
+	return opts
+}
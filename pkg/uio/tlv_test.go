@@ -0,0 +1,251 @@
+// Copyright 2018 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package uio
+
+import (
+	"errors"
+	"io"
+	"reflect"
+	"testing"
+)
+
+func TestPadTLV(t *testing.T) {
+	const tcpNOP = 1
+	l := NewBigEndianBuffer(nil)
+	// Maximum segment size: 1460.
+	l.WriteBytes([]byte{2, 4, 0x05, 0xb4})
+	l.PadTLV(tcpNOP)
+	// Window scale: 7.
+	l.WriteBytes([]byte{3, 3, 7})
+	l.PadTLV(tcpNOP)
+	want := []byte{2, 4, 0x05, 0xb4, 3, 3, 7, tcpNOP}
+	if got := l.Data(); !reflect.DeepEqual(got, want) {
+		t.Errorf("PadTLV() = %#v, want %#v", got, want)
+	}
+	if err := l.Error(); err != nil {
+		t.Errorf("Error() = %v, want nil", err)
+	}
+}
+
+func TestTLV(t *testing.T) {
+	type record struct {
+		typ   uint64
+		value []byte
+	}
+	for i, tt := range []struct {
+		in        []byte
+		typeWidth int
+		lenWidth  int
+		maxAlloc  int
+		want      []record
+		err       error
+	}{
+		{
+			in:        []byte{0x01, 0x02, 'h', 'i', 0x02, 0x00, 0x03, 0x01, 'x'},
+			typeWidth: 1,
+			lenWidth:  1,
+			want:      []record{{1, []byte("hi")}, {2, []byte{}}, {3, []byte("x")}},
+		},
+		{
+			// LLDP-style big-endian 2-byte type and length.
+			in:        []byte{0x00, 0x07, 0x00, 0x03, 'a', 'b', 'c'},
+			typeWidth: 2,
+			lenWidth:  2,
+			want:      []record{{7, []byte("abc")}},
+		},
+		{
+			in:        nil,
+			typeWidth: 1,
+			lenWidth:  1,
+		},
+		{
+			in:        []byte{0x01, 0x01, 'a', 0x02},
+			typeWidth: 1,
+			lenWidth:  1,
+			want:      []record{{1, []byte("a")}},
+			err:       io.ErrUnexpectedEOF,
+		},
+		{
+			in:        []byte{0x01, 0x03, 'a', 'b'},
+			typeWidth: 1,
+			lenWidth:  1,
+			err:       io.ErrUnexpectedEOF,
+		},
+		{
+			in:        []byte{0x01, 0x03, 'a', 'b', 'c'},
+			typeWidth: 1,
+			lenWidth:  1,
+			maxAlloc:  2,
+			err:       ErrTooLong,
+		},
+		{
+			in:        []byte{0x01, 0x00},
+			typeWidth: 0,
+			lenWidth:  1,
+			err:       ErrInvalidSize,
+		},
+	} {
+		l := NewBigEndianBuffer(tt.in)
+		l.SetMaxAlloc(tt.maxAlloc)
+		var got []record
+		for typ, v, ok := l.TLV(tt.typeWidth, tt.lenWidth); ok; typ, v, ok = l.TLV(tt.typeWidth, tt.lenWidth) {
+			got = append(got, record{typ, v.ReadAll()})
+			if err := v.Error(); err != nil {
+				t.Errorf("#%d: value Error() = %v, want nil", i, err)
+			}
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("#%d: TLV() = %v, want %v", i, got, tt.want)
+		}
+		if err := l.Error(); !errors.Is(err, tt.err) {
+			t.Errorf("#%d: Error() = %v, want %v", i, err, tt.err)
+		}
+	}
+}
+
+func TestWriteTLV(t *testing.T) {
+	l := NewBigEndianBuffer(nil)
+	l.WriteTLV(1, 1, 53, []byte{1})
+	l.WriteTLVFunc(1, 1, 43, func(l *Lexer) {
+		l.WriteTLV(1, 1, 1, []byte("id"))
+		l.WriteTLVFunc(2, 2, 2, func(l *Lexer) {
+			l.WriteString("abc")
+		})
+	})
+	l.WriteTLV(2, 4, 0x1234, nil)
+	if err := l.Error(); err != nil {
+		t.Fatalf("Error() = %v, want nil", err)
+	}
+	want := []byte{
+		53, 1, 1,
+		43, 11,
+		1, 2, 'i', 'd',
+		0x00, 0x02, 0x00, 0x03, 'a', 'b', 'c',
+		0x12, 0x34, 0x00, 0x00, 0x00, 0x00,
+	}
+	if got := l.Data(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("WriteTLV() = %#v, want %#v", got, want)
+	}
+
+	typ, v, ok := l.TLV(1, 1)
+	if !ok || typ != 53 {
+		t.Fatalf("TLV() = %d, %t, want 53, true", typ, ok)
+	}
+	typ, v, ok = l.TLV(1, 1)
+	if !ok || typ != 43 {
+		t.Fatalf("TLV() = %d, %t, want 43, true", typ, ok)
+	}
+	if typ, sub, ok := v.TLV(1, 1); !ok || typ != 1 || string(sub.ReadAll()) != "id" {
+		t.Errorf("nested TLV() = %d, %t, want 1, true with value %q", typ, ok, "id")
+	}
+}
+
+func TestWriteTLVErrors(t *testing.T) {
+	for i, tt := range []struct {
+		write func(l *Lexer)
+		want  error
+	}{
+		{write: func(l *Lexer) { l.WriteTLV(0, 1, 1, nil) }, want: ErrInvalidSize},
+		{write: func(l *Lexer) { l.WriteTLV(1, 9, 1, nil) }, want: ErrInvalidSize},
+		{write: func(l *Lexer) { l.WriteTLV(1, 1, 256, nil) }, want: ErrOverflow},
+		{write: func(l *Lexer) { l.WriteTLV(1, 1, 1, make([]byte, 256)) }, want: ErrTooLong},
+		{write: func(l *Lexer) { l.WriteTLVFunc(1, 2, 0x100, func(*Lexer) {}) }, want: ErrOverflow},
+		{write: func(l *Lexer) {
+			l.WriteTLVFunc(1, 1, 1, func(l *Lexer) { l.WriteZeroes(256) })
+		}, want: ErrTooLong},
+	} {
+		l := NewBigEndianBuffer([]byte{0xff})
+		tt.write(l)
+		if err := l.Error(); !errors.Is(err, tt.want) {
+			t.Errorf("#%d: Error() = %v, want %v", i, err, tt.want)
+		}
+		if got, want := l.Data(), []byte{0xff}; !reflect.DeepEqual(got, want) {
+			t.Errorf("#%d: Data() = %#v, want %#v", i, got, want)
+		}
+	}
+}
+
+func TestReadOptionMap(t *testing.T) {
+	for i, tt := range []struct {
+		in        []byte
+		codeWidth int
+		lenWidth  int
+		skip      []uint8
+		want      map[uint8][]byte
+		err       error
+		left      int
+	}{
+		{
+			in:        []byte{53, 1, 1, 12, 3, 'f', 'o', 'o', 255, 0, 0},
+			codeWidth: 1,
+			lenWidth:  1,
+			want:      map[uint8][]byte{53: {1}, 12: []byte("foo")},
+			left:      2,
+		},
+		{
+			// DHCP options with pad options between and after them.
+			in:        []byte{0, 53, 1, 1, 0, 0, 12, 3, 'f', 'o', 'o', 0, 255, 0, 0},
+			codeWidth: 1,
+			lenWidth:  1,
+			skip:      []uint8{0},
+			want:      map[uint8][]byte{53: {1}, 12: []byte("foo")},
+			left:      2,
+		},
+		{
+			in:        []byte{0x00, 0x01, 0x00, 0x07, 0x00, 0x02, 0x00, 0x01, 'a'},
+			codeWidth: 2,
+			lenWidth:  2,
+			skip:      []uint8{1, 7},
+			want:      map[uint8][]byte{2: []byte("a")},
+		},
+		{
+			in:        []byte{12, 2, 'f', 'o', 80, 0, 12, 1, 'o'},
+			codeWidth: 1,
+			lenWidth:  1,
+			want:      map[uint8][]byte{12: []byte("foo"), 80: {}},
+		},
+		{
+			in:        []byte{0x00, 0x01, 0x00, 0x01, 'a', 0x00, 0xff},
+			codeWidth: 2,
+			lenWidth:  2,
+			want:      map[uint8][]byte{1: []byte("a")},
+		},
+		{
+			in:        []byte{0x00, 0x01, 0x00, 0x01, 'a', 0x01, 0x00, 0x00, 0x00},
+			codeWidth: 2,
+			lenWidth:  2,
+			want:      map[uint8][]byte{1: []byte("a")},
+			err:       ErrOverflow,
+			left:      2,
+		},
+		{
+			in:        []byte{53, 1, 1, 12, 3, 'f'},
+			codeWidth: 1,
+			lenWidth:  1,
+			want:      map[uint8][]byte{53: {1}},
+			err:       io.ErrUnexpectedEOF,
+			left:      1,
+		},
+		{
+			in:        []byte{53, 1, 1},
+			codeWidth: 1,
+			lenWidth:  0,
+			want:      map[uint8][]byte{},
+			err:       ErrInvalidSize,
+			left:      3,
+		},
+	} {
+		l := NewBigEndianBuffer(tt.in)
+		if got := l.ReadOptionMap(tt.codeWidth, tt.lenWidth, 255, tt.skip...); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("#%d: ReadOptionMap() = %v, want %v", i, got, tt.want)
+		}
+		if err := l.Error(); !errors.Is(err, tt.err) {
+			t.Errorf("#%d: Error() = %v, want %v", i, err, tt.err)
+		}
+		if l.Len() != tt.left {
+			t.Errorf("#%d: Len() = %d, want %d", i, l.Len(), tt.left)
+		}
+	}
+}
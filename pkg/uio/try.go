@@ -0,0 +1,56 @@
+// Copyright 2018 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package uio
+
+// errorSince returns the last error set after the errs'th one, or nil.
+func (l *Lexer) errorSince(errs int) error {
+	if len(l.errs) > errs {
+		return l.errs[len(l.errs)-1]
+	}
+	return nil
+}
+
+// TryRead8 is like Read8, but also returns the error caused by this read,
+// if any, for parsers whose control flow depends on the value.
+//
+// The error is still recorded, so a later call to Error() sees it too.
+// Errors from earlier reads are not returned.
+func (l *Lexer) TryRead8() (uint8, error) {
+	errs := len(l.errs)
+	v := l.Read8()
+	return v, l.errorSince(errs)
+}
+
+// TryRead16 is like Read16, but also returns the error caused by this read.
+// See TryRead8.
+func (l *Lexer) TryRead16() (uint16, error) {
+	errs := len(l.errs)
+	v := l.Read16()
+	return v, l.errorSince(errs)
+}
+
+// TryRead32 is like Read32, but also returns the error caused by this read.
+// See TryRead8.
+func (l *Lexer) TryRead32() (uint32, error) {
+	errs := len(l.errs)
+	v := l.Read32()
+	return v, l.errorSince(errs)
+}
+
+// TryRead64 is like Read64, but also returns the error caused by this read.
+// See TryRead8.
+func (l *Lexer) TryRead64() (uint64, error) {
+	errs := len(l.errs)
+	v := l.Read64()
+	return v, l.errorSince(errs)
+}
+
+// TryCopyN is like CopyN, but also returns the error caused by this read.
+// See TryRead8.
+func (l *Lexer) TryCopyN(n int) ([]byte, error) {
+	errs := len(l.errs)
+	v := l.CopyN(n)
+	return v, l.errorSince(errs)
+}
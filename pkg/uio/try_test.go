@@ -0,0 +1,50 @@
+// Copyright 2018 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package uio
+
+import (
+	"errors"
+	"io"
+	"reflect"
+	"testing"
+)
+
+func TestTryRead(t *testing.T) {
+	l := NewBigEndianBuffer([]byte{
+		0x01,
+		0x02, 0x03,
+		0x04, 0x05, 0x06, 0x07,
+		0x08, 0x09, 0x0a, 0x0b, 0x0c, 0x0d, 0x0e, 0x0f,
+		0xaa, 0xbb,
+	})
+	if v, err := l.TryRead8(); v != 0x01 || err != nil {
+		t.Errorf("TryRead8() = %#x, %v, want 0x1, nil", v, err)
+	}
+	if v, err := l.TryRead16(); v != 0x0203 || err != nil {
+		t.Errorf("TryRead16() = %#x, %v, want 0x203, nil", v, err)
+	}
+	if v, err := l.TryRead32(); v != 0x04050607 || err != nil {
+		t.Errorf("TryRead32() = %#x, %v, want 0x4050607, nil", v, err)
+	}
+	if v, err := l.TryRead64(); v != 0x08090a0b0c0d0e0f || err != nil {
+		t.Errorf("TryRead64() = %#x, %v, want 0x8090a0b0c0d0e0f, nil", v, err)
+	}
+
+	// Errors are returned immediately, and also recorded.
+	if _, err := l.TryRead32(); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("TryRead32() = %v, want %v", err, io.ErrUnexpectedEOF)
+	}
+	if err := l.Error(); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("Error() = %v, want %v", err, io.ErrUnexpectedEOF)
+	}
+
+	// Earlier errors don't make later reads fail.
+	if v, err := l.TryCopyN(2); !reflect.DeepEqual(v, []byte{0xaa, 0xbb}) || err != nil {
+		t.Errorf("TryCopyN(2) = %#v, %v, want %#v, nil", v, err, []byte{0xaa, 0xbb})
+	}
+	if _, err := l.TryCopyN(1); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("TryCopyN(1) = %v, want %v", err, io.ErrUnexpectedEOF)
+	}
+}
@@ -0,0 +1,232 @@
+// Copyright 2018 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package uio
+
+import (
+	"encoding/binary"
+	"io"
+	"math"
+)
+
+// ReadULEB128 reads an unsigned LEB128-encoded value from the Buffer.
+//
+// LEB128 stores 7 bits per byte, least significant group first, and is not
+// affected by the Lexer's byte order.
+//
+// If the Buffer ends before the last byte of the value, Error() will return
+// io.ErrUnexpectedEOF. If the value does not fit in 64 bits, Error() will
+// return ErrOverflow. In strict mode, a value encoded in more bytes than
+// necessary sets ErrNonCanonical.
+func (l *Lexer) ReadULEB128() uint64 {
+	var v uint64
+	for shift := uint(0); ; shift += 7 {
+		b := l.consume(1)
+		if b == nil {
+			return 0
+		}
+		c := b[0]
+		if shift == 63 && c > 1 {
+			l.setError(ErrOverflow)
+			return 0
+		}
+		v |= uint64(c&0x7f) << shift
+		if c&0x80 == 0 {
+			if l.strict && shift > 0 && c == 0 {
+				l.setError(ErrNonCanonical)
+				return 0
+			}
+			return v
+		}
+	}
+}
+
+// WriteULEB128 writes v to the Buffer as an unsigned LEB128-encoded value.
+func (l *Lexer) WriteULEB128(v uint64) {
+	for v >= 0x80 {
+		l.Write8(byte(v) | 0x80)
+		v >>= 7
+	}
+	l.Write8(byte(v))
+}
+
+// ReadVarBytes reads a copy of a byte slice prefixed by its ULEB128-encoded
+// length, as used for protocol buffers bytes fields.
+//
+// If the length is greater than the limit set by SetMaxAlloc, only the
+// length is consumed and Error() will return ErrTooLong. If an error
+// occurred, Error() will return a non-nil error.
+func (l *Lexer) ReadVarBytes() []byte {
+	errs := len(l.errs)
+	n := l.ReadULEB128()
+	if len(l.errs) > errs {
+		return nil
+	}
+	return l.readPrefixed(n)
+}
+
+// WriteVarBytes writes p prefixed by its ULEB128-encoded length.
+func (l *Lexer) WriteVarBytes(p []byte) {
+	l.WriteULEB128(uint64(len(p)))
+	l.WriteBytes(p)
+}
+
+// ReadSLEB128 reads a signed LEB128-encoded value from the Buffer.
+//
+// The value is sign-extended from bit 6 of its last byte, as in DWARF. This
+// is not the zigzag encoding used by protocol buffers.
+//
+// If the Buffer ends before the last byte of the value, Error() will return
+// io.ErrUnexpectedEOF. If the value does not fit in 64 bits, Error() will
+// return ErrOverflow. In strict mode, a value encoded in more bytes than
+// necessary sets ErrNonCanonical.
+func (l *Lexer) ReadSLEB128() int64 {
+	var v int64
+	var prev byte
+	for shift := uint(0); ; shift += 7 {
+		b := l.consume(1)
+		if b == nil {
+			return 0
+		}
+		c := b[0]
+		// Only the sign can be left for the tenth byte.
+		if shift == 63 && c != 0x00 && c != 0x7f {
+			l.setError(ErrOverflow)
+			return 0
+		}
+		v |= int64(c&0x7f) << shift
+		if c&0x80 == 0 {
+			// The last byte is redundant if it only repeats the sign
+			// bit of the one before.
+			if l.strict && shift > 0 && (c == 0x00 && prev&0x40 == 0 || c == 0x7f && prev&0x40 != 0) {
+				l.setError(ErrNonCanonical)
+				return 0
+			}
+			if shift+7 < 64 && c&0x40 != 0 {
+				v |= -1 << (shift + 7)
+			}
+			return v
+		}
+		prev = c
+	}
+}
+
+// WriteSLEB128 writes v to the Buffer as a signed LEB128-encoded value.
+func (l *Lexer) WriteSLEB128(v int64) {
+	for {
+		c := byte(v & 0x7f)
+		v >>= 7
+		if (v == 0 && c&0x40 == 0) || (v == -1 && c&0x40 != 0) {
+			l.Write8(c)
+			return
+		}
+		l.Write8(c | 0x80)
+	}
+}
+
+// ReadZigzag32 reads a protocol buffers zigzag-encoded sint32 from the Buffer.
+//
+// Zigzag maps signed integers to unsigned ones (0, -1, 1, -2 become 0, 1, 2,
+// 3) before encoding them as unsigned LEB128. It is not the same as signed
+// LEB128; see ReadSLEB128 for that.
+//
+// If the encoding is longer than 5 bytes or does not fit in 32 bits, Error()
+// will return ErrOverflow.
+func (l *Lexer) ReadZigzag32() int32 {
+	errs, start := len(l.errs), l.Tell()
+	v := l.ReadULEB128()
+	if len(l.errs) == errs && (l.Tell()-start > 5 || v > math.MaxUint32) {
+		l.setError(ErrOverflow)
+		return 0
+	}
+	u := uint32(v)
+	return int32(u>>1) ^ -int32(u&1)
+}
+
+// ReadZigzag64 reads a protocol buffers zigzag-encoded sint64 from the Buffer.
+//
+// See ReadZigzag32 for details of the encoding.
+//
+// If the encoding is longer than 10 bytes or does not fit in 64 bits,
+// Error() will return ErrOverflow.
+func (l *Lexer) ReadZigzag64() int64 {
+	u := l.ReadULEB128()
+	return int64(u>>1) ^ -int64(u&1)
+}
+
+// WriteZigzag32 writes v to the Buffer as a protocol buffers zigzag-encoded
+// sint32.
+func (l *Lexer) WriteZigzag32(v int32) {
+	l.WriteULEB128(uint64(uint32(v<<1) ^ uint32(v>>31)))
+}
+
+// WriteZigzag64 writes v to the Buffer as a protocol buffers zigzag-encoded
+// sint64.
+func (l *Lexer) WriteZigzag64(v int64) {
+	l.WriteULEB128(uint64(v<<1) ^ uint64(v>>63))
+}
+
+// ReadUvarint reads an unsigned varint in the format of encoding/binary, as
+// written by binary.PutUvarint, accepting exactly what binary.Uvarint does.
+//
+// The format is that of unsigned LEB128, but unlike ReadULEB128, nothing is
+// consumed on error. Use ReadUvarint for data produced by Go encoders and
+// ReadULEB128 for formats specified in terms of LEB128, such as DWARF or
+// WebAssembly.
+//
+// If the Buffer ends before the last byte of the value, Error() will return
+// io.ErrUnexpectedEOF. If the value does not fit in 64 bits, as for
+// binary.Uvarint, Error() will return ErrOverflow. In strict mode, a value
+// encoded in more bytes than necessary sets ErrNonCanonical.
+func (l *Lexer) ReadUvarint() uint64 {
+	l.Has(binary.MaxVarintLen64)
+	d := l.Data()
+	v, n := binary.Uvarint(d)
+	switch {
+	case n == 0:
+		l.setError(io.ErrUnexpectedEOF)
+		return 0
+	case n < 0:
+		l.setError(ErrOverflow)
+		return 0
+	case l.strict && n > 1 && d[n-1] == 0:
+		l.setError(ErrNonCanonical)
+		return 0
+	}
+	l.consume(n)
+	return v
+}
+
+// ReadVarint reads a signed varint in the format of encoding/binary, as
+// written by binary.PutVarint, accepting exactly what binary.Varint does.
+//
+// Signed varints are zigzag-encoded, like ReadZigzag64, and unlike
+// ReadSLEB128.
+//
+// Errors are set as for ReadUvarint.
+func (l *Lexer) ReadVarint() int64 {
+	u := l.ReadUvarint()
+	v := int64(u >> 1)
+	if u&1 != 0 {
+		v = ^v
+	}
+	return v
+}
+
+// WriteUvarint writes v as an unsigned varint in the format of
+// encoding/binary.
+//
+// If an error occurred, Error() will return a non-nil error.
+func (l *Lexer) WriteUvarint(v uint64) {
+	var b [binary.MaxVarintLen64]byte
+	l.WriteBytes(b[:binary.PutUvarint(b[:], v)])
+}
+
+// WriteVarint writes v as a signed varint in the format of encoding/binary.
+//
+// If an error occurred, Error() will return a non-nil error.
+func (l *Lexer) WriteVarint(v int64) {
+	var b [binary.MaxVarintLen64]byte
+	l.WriteBytes(b[:binary.PutVarint(b[:], v)])
+}
@@ -0,0 +1,328 @@
+// Copyright 2018 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package uio
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"reflect"
+	"testing"
+	"testing/iotest"
+)
+
+func TestULEB128(t *testing.T) {
+	for i, tt := range []struct {
+		v    uint64
+		want []byte
+	}{
+		{v: 0, want: []byte{0x00}},
+		{v: 1, want: []byte{0x01}},
+		{v: 127, want: []byte{0x7f}},
+		{v: 128, want: []byte{0x80, 0x01}},
+		{v: 624485, want: []byte{0xe5, 0x8e, 0x26}},
+		{v: math.MaxUint32, want: []byte{0xff, 0xff, 0xff, 0xff, 0x0f}},
+		{v: math.MaxUint64, want: []byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x01}},
+	} {
+		t.Run(fmt.Sprintf("Test #%02d", i), func(t *testing.T) {
+			l := NewLittleEndianBuffer(nil)
+			l.WriteULEB128(tt.v)
+			if got := l.Data(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("WriteULEB128(%d) = %#v, want %#v", tt.v, got, tt.want)
+			}
+			if got := l.ReadULEB128(); got != tt.v {
+				t.Errorf("ReadULEB128() = %d, want %d", got, tt.v)
+			}
+			if err := l.Error(); err != nil {
+				t.Errorf("Error() = %v, want nil", err)
+			}
+		})
+	}
+}
+
+func TestULEB128Errors(t *testing.T) {
+	for i, tt := range []struct {
+		in  []byte
+		err error
+	}{
+		{in: nil, err: io.ErrUnexpectedEOF},
+		{in: []byte{0x80}, err: io.ErrUnexpectedEOF},
+		{in: []byte{0xff, 0xff, 0xff}, err: io.ErrUnexpectedEOF},
+		{in: []byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x02}, err: ErrOverflow},
+		{in: []byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x81, 0x00}, err: ErrOverflow},
+	} {
+		t.Run(fmt.Sprintf("Test #%02d", i), func(t *testing.T) {
+			l := NewBigEndianBuffer(tt.in)
+			if got := l.ReadULEB128(); got != 0 {
+				t.Errorf("ReadULEB128() = %d, want 0", got)
+			}
+			if err := l.Error(); !errors.Is(err, tt.err) {
+				t.Errorf("Error() = %v, want %v", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestSLEB128(t *testing.T) {
+	for i, tt := range []struct {
+		v    int64
+		want []byte
+	}{
+		{v: 0, want: []byte{0x00}},
+		{v: 1, want: []byte{0x01}},
+		{v: -1, want: []byte{0x7f}},
+		{v: 63, want: []byte{0x3f}},
+		{v: -64, want: []byte{0x40}},
+		{v: 64, want: []byte{0xc0, 0x00}},
+		{v: -65, want: []byte{0xbf, 0x7f}},
+		{v: 127, want: []byte{0xff, 0x00}},
+		{v: -128, want: []byte{0x80, 0x7f}},
+		{v: -123456, want: []byte{0xc0, 0xbb, 0x78}},
+		{v: math.MaxInt32, want: []byte{0xff, 0xff, 0xff, 0xff, 0x07}},
+		{v: math.MinInt32, want: []byte{0x80, 0x80, 0x80, 0x80, 0x78}},
+		{v: math.MaxInt64, want: []byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x00}},
+		{v: math.MinInt64, want: []byte{0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x7f}},
+	} {
+		t.Run(fmt.Sprintf("Test #%02d", i), func(t *testing.T) {
+			l := NewLittleEndianBuffer(nil)
+			l.WriteSLEB128(tt.v)
+			if got := l.Data(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("WriteSLEB128(%d) = %#v, want %#v", tt.v, got, tt.want)
+			}
+			if got := l.ReadSLEB128(); got != tt.v {
+				t.Errorf("ReadSLEB128() = %d, want %d", got, tt.v)
+			}
+			if err := l.Error(); err != nil {
+				t.Errorf("Error() = %v, want nil", err)
+			}
+		})
+	}
+}
+
+func TestSLEB128Errors(t *testing.T) {
+	for i, tt := range []struct {
+		in  []byte
+		err error
+	}{
+		{in: nil, err: io.ErrUnexpectedEOF},
+		{in: []byte{0xc0}, err: io.ErrUnexpectedEOF},
+		{in: []byte{0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x01}, err: ErrOverflow},
+		{in: []byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x80, 0x00}, err: ErrOverflow},
+	} {
+		t.Run(fmt.Sprintf("Test #%02d", i), func(t *testing.T) {
+			l := NewBigEndianBuffer(tt.in)
+			if got := l.ReadSLEB128(); got != 0 {
+				t.Errorf("ReadSLEB128() = %d, want 0", got)
+			}
+			if err := l.Error(); !errors.Is(err, tt.err) {
+				t.Errorf("Error() = %v, want %v", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestZigzag(t *testing.T) {
+	for i, tt := range []struct {
+		v    int64
+		want []byte
+	}{
+		{v: 0, want: []byte{0x00}},
+		{v: -1, want: []byte{0x01}},
+		{v: 1, want: []byte{0x02}},
+		{v: -2, want: []byte{0x03}},
+		{v: -64, want: []byte{0x7f}},
+		{v: 64, want: []byte{0x80, 0x01}},
+		{v: math.MaxInt32, want: []byte{0xfe, 0xff, 0xff, 0xff, 0x0f}},
+		{v: math.MinInt32, want: []byte{0xff, 0xff, 0xff, 0xff, 0x0f}},
+	} {
+		t.Run(fmt.Sprintf("Test #%02d", i), func(t *testing.T) {
+			l := NewLittleEndianBuffer(nil)
+			l.WriteZigzag32(int32(tt.v))
+			if got := l.Data(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("WriteZigzag32(%d) = %#v, want %#v", tt.v, got, tt.want)
+			}
+			l.WriteZigzag64(tt.v)
+			if got := l.ReadZigzag32(); got != int32(tt.v) {
+				t.Errorf("ReadZigzag32() = %d, want %d", got, tt.v)
+			}
+			if got := l.ReadZigzag64(); got != tt.v {
+				t.Errorf("ReadZigzag64() = %d, want %d", got, tt.v)
+			}
+			if err := l.Error(); err != nil {
+				t.Errorf("Error() = %v, want nil", err)
+			}
+		})
+	}
+
+	l := NewLittleEndianBuffer(nil)
+	l.WriteZigzag64(math.MinInt64)
+	l.WriteZigzag64(math.MaxInt64)
+	if got := l.ReadZigzag64(); got != math.MinInt64 {
+		t.Errorf("ReadZigzag64() = %d, want %d", got, int64(math.MinInt64))
+	}
+	if got := l.ReadZigzag64(); got != math.MaxInt64 {
+		t.Errorf("ReadZigzag64() = %d, want %d", got, int64(math.MaxInt64))
+	}
+}
+
+func TestZigzag32Overflow(t *testing.T) {
+	for i, in := range [][]byte{
+		// Does not fit in 32 bits.
+		{0xff, 0xff, 0xff, 0xff, 0x1f},
+		// Fits in 32 bits, but longer than 5 bytes.
+		{0x80, 0x80, 0x80, 0x80, 0x80, 0x00},
+	} {
+		t.Run(fmt.Sprintf("Test #%02d", i), func(t *testing.T) {
+			for _, l := range []*Lexer{
+				NewLittleEndianBuffer(in),
+				NewReaderLexer(iotest.OneByteReader(bytes.NewReader(in)), binary.LittleEndian),
+			} {
+				if got := l.ReadZigzag32(); got != 0 {
+					t.Errorf("ReadZigzag32() = %d, want 0", got)
+				}
+				if err := l.Error(); !errors.Is(err, ErrOverflow) {
+					t.Errorf("Error() = %v, want %v", err, ErrOverflow)
+				}
+			}
+
+			// An earlier error does not skip the check.
+			l := NewLittleEndianBuffer(append([]byte{0x09}, in...))
+			l.ReadEnum8(0)
+			l.ReadZigzag32()
+			if errs := l.Errors(); len(errs) != 2 || !errors.Is(errs[1], ErrOverflow) {
+				t.Errorf("ReadZigzag32() after an error: Errors() = %v, want a second error %v", errs, ErrOverflow)
+			}
+		})
+	}
+}
+
+func TestVarBytes(t *testing.T) {
+	for i, tt := range []struct {
+		p      []byte
+		prefix []byte
+	}{
+		{p: []byte{}, prefix: []byte{0x00}},
+		{p: []byte("hello"), prefix: []byte{0x05}},
+		{p: make([]byte, 127), prefix: []byte{0x7f}},
+		{p: make([]byte, 128), prefix: []byte{0x80, 0x01}},
+		{p: make([]byte, 300), prefix: []byte{0xac, 0x02}},
+	} {
+		t.Run(fmt.Sprintf("Test #%02d", i), func(t *testing.T) {
+			l := NewLittleEndianBuffer(nil)
+			l.WriteVarBytes(tt.p)
+			want := append(append([]byte(nil), tt.prefix...), tt.p...)
+			if got := l.Data(); !reflect.DeepEqual(got, want) {
+				t.Errorf("WriteVarBytes() = %#v, want %#v", got, want)
+			}
+			if got := l.ReadVarBytes(); !reflect.DeepEqual(got, tt.p) {
+				t.Errorf("ReadVarBytes() = %#v, want %#v", got, tt.p)
+			}
+			if err := l.Error(); err != nil {
+				t.Errorf("Error() = %v, want nil", err)
+			}
+			if l.Len() != 0 {
+				t.Errorf("Len() = %d, want 0", l.Len())
+			}
+		})
+	}
+}
+
+func TestReadVarBytesErrors(t *testing.T) {
+	for i, tt := range []struct {
+		in       []byte
+		maxAlloc int
+		want     error
+		left     int
+	}{
+		{in: []byte{0x80}, want: io.ErrUnexpectedEOF},
+		{in: []byte{0x03, 'a', 'b'}, want: io.ErrUnexpectedEOF, left: 2},
+		{in: []byte{0xff, 0xff, 0xff, 0xff, 0x0f, 'a'}, maxAlloc: 16, want: ErrTooLong, left: 1},
+		{in: []byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x01}, want: io.ErrUnexpectedEOF},
+	} {
+		l := NewLittleEndianBuffer(tt.in)
+		l.SetMaxAlloc(tt.maxAlloc)
+		if got := l.ReadVarBytes(); got != nil {
+			t.Errorf("#%d: ReadVarBytes() = %#v, want nil", i, got)
+		}
+		if err := l.Error(); !errors.Is(err, tt.want) {
+			t.Errorf("#%d: Error() = %v, want %v", i, err, tt.want)
+		}
+		if l.Len() != tt.left {
+			t.Errorf("#%d: Len() = %d, want %d", i, l.Len(), tt.left)
+		}
+	}
+}
+
+func TestUvarint(t *testing.T) {
+	for i, v := range []uint64{0, 1, 127, 128, 300, math.MaxUint32, math.MaxUint64} {
+		want := make([]byte, binary.MaxVarintLen64)
+		want = want[:binary.PutUvarint(want, v)]
+
+		l := NewLittleEndianBuffer(nil)
+		l.WriteUvarint(v)
+		if got := l.Data(); !reflect.DeepEqual(got, want) {
+			t.Errorf("#%d: WriteUvarint(%d) = %#v, want %#v", i, v, got, want)
+		}
+		if got := l.ReadUvarint(); got != v {
+			t.Errorf("#%d: ReadUvarint() = %d, want %d", i, got, v)
+		}
+		if err := l.Error(); err != nil {
+			t.Errorf("#%d: Error() = %v, want nil", i, err)
+		}
+	}
+}
+
+func TestVarint(t *testing.T) {
+	for i, v := range []int64{0, 1, -1, 63, -64, 64, math.MaxInt64, math.MinInt64} {
+		want := make([]byte, binary.MaxVarintLen64)
+		want = want[:binary.PutVarint(want, v)]
+
+		l := NewLittleEndianBuffer(nil)
+		l.WriteVarint(v)
+		if got := l.Data(); !reflect.DeepEqual(got, want) {
+			t.Errorf("#%d: WriteVarint(%d) = %#v, want %#v", i, v, got, want)
+		}
+		if got := l.ReadVarint(); got != v {
+			t.Errorf("#%d: ReadVarint() = %d, want %d", i, got, v)
+		}
+		if err := l.Error(); err != nil {
+			t.Errorf("#%d: Error() = %v, want nil", i, err)
+		}
+	}
+}
+
+func TestUvarintMatchesBinary(t *testing.T) {
+	for i, in := range [][]byte{
+		{},
+		{0x80},
+		{0x80, 0x00},
+		{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x01},
+		{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x02},
+		{0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x00},
+	} {
+		want, n := binary.Uvarint(in)
+		l := NewLittleEndianBuffer(in)
+		got := l.ReadUvarint()
+		var wantErr error
+		switch {
+		case n == 0:
+			wantErr = io.ErrUnexpectedEOF
+		case n < 0:
+			wantErr, want = ErrOverflow, 0
+		}
+		if got != want {
+			t.Errorf("#%d: ReadUvarint() = %d, want %d", i, got, want)
+		}
+		if err := l.Error(); !errors.Is(err, wantErr) {
+			t.Errorf("#%d: Error() = %v, want %v", i, err, wantErr)
+		}
+		if n > 0 && l.Len() != len(in)-n {
+			t.Errorf("#%d: Len() = %d, want %d", i, l.Len(), len(in)-n)
+		}
+	}
+}
@@ -0,0 +1,67 @@
+// Copyright 2018 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package uio
+
+import (
+	"crypto/cipher"
+	"io"
+)
+
+// xformReader reads the remaining bytes of a Lexer, transformed.
+type xformReader struct {
+	l         *Lexer
+	transform func([]byte)
+}
+
+// Read implements io.Reader.
+func (r *xformReader) Read(p []byte) (int, error) {
+	n := r.l.ReadAllInto(p)
+	if n == 0 && len(p) > 0 {
+		if r.l.rerr != nil && r.l.rerr != io.EOF {
+			return 0, r.l.rerr
+		}
+		return 0, io.EOF
+	}
+	r.transform(p[:n])
+	return n, nil
+}
+
+// NewXFormLexer returns a Lexer, with the same byte order as underlying,
+// that lazily reads the remaining bytes of underlying after passing them
+// through transform, like NewReaderLexer. It can be used to descramble or
+// decrypt a payload so that parsers see the plaintext:
+//
+//	l := NewXFormLexer(payload, XORTransform(key))
+//
+// transform is called on successive chunks of the data, in order, and must
+// change them in place. The bytes of underlying are not changed; they are
+// consumed as the returned Lexer reads them.
+func NewXFormLexer(underlying *Lexer, transform func([]byte)) *Lexer {
+	return NewReaderLexer(&xformReader{l: underlying, transform: transform}, underlying.order)
+}
+
+// XORTransform returns a transform for NewXFormLexer that XORs the data with
+// key, repeated as needed. It keeps its position in key across calls, so it
+// must only be used for one Lexer. An empty key leaves the data unchanged.
+func XORTransform(key []byte) func([]byte) {
+	i := 0
+	return func(p []byte) {
+		if len(key) == 0 {
+			return
+		}
+		for j := range p {
+			p[j] ^= key[i]
+			i = (i + 1) % len(key)
+		}
+	}
+}
+
+// StreamTransform returns a transform for NewXFormLexer that XORs the data
+// with the key stream of s, e.g. to decrypt AES-CTR or RC4.
+func StreamTransform(s cipher.Stream) func([]byte) {
+	return func(p []byte) {
+		s.XORKeyStream(p, p)
+	}
+}
@@ -0,0 +1,73 @@
+// Copyright 2018 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package uio
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/binary"
+	"errors"
+	"io"
+	"testing"
+	"testing/iotest"
+)
+
+func TestXFormLexerXOR(t *testing.T) {
+	key := []byte{0x5a, 0xa5, 0x0f}
+	plain := []byte("\x00\x05hello\x12\x34\x56\x78")
+	scrambled := append([]byte(nil), plain...)
+	XORTransform(key)(scrambled)
+
+	u := NewBigEndianBuffer(append([]byte{0xff}, scrambled...))
+	u.Read8()
+	l := NewXFormLexer(u, XORTransform(key))
+	if got, want := l.ReadLengthPrefixedString16(), "hello"; got != want {
+		t.Errorf("ReadLengthPrefixedString16() = %q, want %q", got, want)
+	}
+	if got, want := l.Read32(), uint32(0x12345678); got != want {
+		t.Errorf("Read32() = %#x, want %#x", got, want)
+	}
+	if !l.Finished() {
+		t.Errorf("Finished() = false, want true")
+	}
+	if err := l.Error(); err != nil {
+		t.Errorf("Error() = %v, want nil", err)
+	}
+	if !u.Finished() {
+		t.Errorf("underlying Finished() = false, want true")
+	}
+	if got := u.Consumed().ReadAll()[1:]; !bytes.Equal(got, scrambled) {
+		t.Errorf("underlying bytes changed to %#v, want %#v", got, scrambled)
+	}
+	l.Read8()
+	if err := l.Error(); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("Read8() past the end: Error() = %v, want %v", err, io.ErrUnexpectedEOF)
+	}
+}
+
+func TestXFormLexerStream(t *testing.T) {
+	block, err := aes.NewCipher(make([]byte, 16))
+	if err != nil {
+		t.Fatal(err)
+	}
+	iv := make([]byte, aes.BlockSize)
+	plain := bytes.Repeat([]byte("firmware"), 100)
+	ciphertext := make([]byte, len(plain))
+	cipher.NewCTR(block, iv).XORKeyStream(ciphertext, plain)
+
+	// Read the ciphertext one byte at a time to split it into many chunks.
+	u := NewReaderLexer(iotest.OneByteReader(bytes.NewReader(ciphertext)), binary.LittleEndian)
+	l := NewXFormLexer(u, StreamTransform(cipher.NewCTR(block, iv)))
+	if l.Order() != binary.LittleEndian {
+		t.Errorf("Order() = %v, want %v", l.Order(), binary.LittleEndian)
+	}
+	if got := append(l.CopyN(3), l.ReadAll()...); !bytes.Equal(got, plain) {
+		t.Errorf("ReadAll() = %q, want %q", got, plain)
+	}
+	if err := l.Error(); err != nil {
+		t.Errorf("Error() = %v, want nil", err)
+	}
+}